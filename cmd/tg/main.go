@@ -1,16 +1,26 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/enrich"
+	"github.com/bf/tg/internal/feedback"
+	"github.com/bf/tg/internal/inbox"
 	"github.com/bf/tg/internal/llm"
+	"github.com/bf/tg/internal/taskwarrior"
 	"github.com/bf/tg/internal/tui"
+	"github.com/bf/tg/internal/usage"
 )
 
 func main() {
@@ -29,6 +39,16 @@ func main() {
 		runEnrich()
 	case "focus":
 		runFocus()
+	case "cache":
+		runCache()
+	case "undo":
+		runUndo()
+	case "prompt":
+		runPrompt()
+	case "learn":
+		runLearn()
+	case "usage":
+		runUsage()
 	case "help", "--help", "-h":
 		printHelp()
 	case "version", "--version", "-v":
@@ -40,27 +60,39 @@ func main() {
 }
 
 func runAdd() {
-	if len(os.Args) < 3 {
-		fmt.Fprintln(os.Stderr, "Usage: tg add <description>")
+	flags := flag.NewFlagSet("add", flag.ExitOnError)
+	split := flags.Bool("split", false, "break the task into dependent subtasks")
+	file := flags.String("file", "", "add every item of a list in this file, - for stdin")
+	flags.Parse(os.Args[2:])
+
+	if *file == "" && flags.NArg() == 1 && flags.Arg(0) == "-" {
+		*file = "-"
+	}
+	if *file != "" {
+		runAddBatch(*file)
+		return
+	}
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: tg add [--split] <description>")
+		fmt.Fprintln(os.Stderr, "       tg add --file <path> | tg add -")
 		os.Exit(1)
 	}
 
 	// Join remaining args as description
-	description := strings.Join(os.Args[2:], " ")
+	description := strings.Join(flags.Args(), " ")
 
-	cfg, err := config.Load()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
-		os.Exit(1)
-	}
+	cfg := loadConfig()
 
-	provider, err := llm.New(cfg)
+	meter := llm.NewMeter(cfg, "add")
+	provider, err := llm.New(cfg, meter)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create LLM provider: %v\n", err)
 		os.Exit(1)
 	}
 
 	model := tui.NewAddModel(cfg, provider, description)
+	model.SetSplit(*split)
 	p := tea.NewProgram(model, tea.WithAltScreen())
 
 	if _, err := p.Run(); err != nil {
@@ -69,39 +101,172 @@ func runAdd() {
 	}
 }
 
-func runEnrich() {
-	var filter string
-	if len(os.Args) > 2 {
-		filter = strings.Join(os.Args[2:], " ")
+// runAddBatch enriches the items of a list in one go and reviews them in a table
+func runAddBatch(path string) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read list: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		r = f
+	}
+	items, err := inbox.Parse(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read list: %v\n", err)
+		os.Exit(1)
+	}
+	if len(items) == 0 {
+		fmt.Fprintln(os.Stderr, "No tasks found in the list")
+		os.Exit(1)
 	}
 
-	cfg, err := config.Load()
+	cfg := loadConfig()
+
+	meter := llm.NewMeter(cfg, "add")
+	provider, err := llm.New(cfg, meter)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Failed to create LLM provider: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if path == "-" {
+		// The list came through stdin, so keys have to come from the terminal
+		opts = append(opts, tea.WithInputTTY())
+	}
+	p := tea.NewProgram(tui.NewBatchModel(cfg, provider, items), opts...)
+
+	if _, err := p.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+}
+
+func runEnrich() {
+	flags := flag.NewFlagSet("enrich", flag.ExitOnError)
+	auto := flags.Bool("auto", false, "enrich without prompting, applying confident results")
+	workers := flags.Int("workers", 0, "parallel LLM calls in --auto mode (default from config)")
+	threshold := flags.Float64("threshold", -1, "confidence below which --auto defers a task for review (default from config)")
+	dryRun := flags.Bool("dry-run", false, "print the task modify commands instead of running them")
+	budget := flags.Float64("budget", -1, "estimated USD after which no more tasks are enriched, 0 for no limit (default from config)")
+	flags.Parse(os.Args[2:])
+
+	filter := strings.Join(flags.Args(), " ")
+
+	cfg := loadConfig()
+	if *budget >= 0 {
+		cfg.Enrich.Budget = *budget
+	}
 
-	provider, err := llm.New(cfg)
+	meter := llm.NewMeter(cfg, "enrich")
+	provider, err := llm.New(cfg, meter)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create LLM provider: %v\n", err)
 		os.Exit(1)
 	}
 
-	model := tui.NewEnrichModel(cfg, provider, filter)
+	if *auto {
+		runEnrichAuto(cfg, provider, meter, filter, *workers, *threshold, *dryRun)
+		return
+	}
+
+	model := tui.NewEnrichModel(cfg, provider, meter, filter)
+	model.SetDryRun(*dryRun)
 	p := tea.NewProgram(model, tea.WithAltScreen())
 
 	if _, err := p.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+
+	for _, command := range model.Plan() {
+		fmt.Println(command)
+	}
 }
 
-func runFocus() {
-	cfg, err := config.Load()
+// runEnrichAuto enriches all matching tasks in parallel without prompting. Progress and the
+// summary go to stderr so a dry-run plan on stdout can be redirected to a script.
+func runEnrichAuto(cfg *config.Config, provider llm.Provider, meter *llm.Meter, filter string, workers int, threshold float64, dryRun bool) {
+	twClient := taskwarrior.New()
+
+	var tasks []taskwarrior.Task
+	var err error
+	if filter != "" {
+		tasks, err = twClient.Export(filter)
+	} else {
+		tasks, err = twClient.GetUntaggedTasks()
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Failed to load tasks: %v\n", err)
 		os.Exit(1)
 	}
+	if len(tasks) == 0 {
+		fmt.Fprintln(os.Stderr, "No tasks to enrich")
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	runner := enrich.NewAuto(cfg, provider, meter)
+	runner.DryRun = dryRun
+	runner.Progress = os.Stderr
+	if workers > 0 {
+		runner.Workers = workers
+	}
+	if threshold >= 0 {
+		runner.Threshold = threshold
+	}
+
+	results := runner.Run(ctx, tasks)
+	stop()
+
+	if dryRun {
+		enrich.WritePlan(os.Stdout, results)
+	}
+	fmt.Fprintln(os.Stderr)
+	enrich.WriteSummary(os.Stderr, results, meter.Spent())
+
+	// Low-confidence tasks get a second, interactive pass with the enrichments already fetched
+	var deferred []taskwarrior.Task
+	enrichments := make(map[string]*llm.Enrichment)
+	for _, r := range results {
+		if r.Outcome == enrich.Deferred {
+			deferred = append(deferred, r.Task)
+			enrichments[r.Task.UUID] = r.Enrichment
+		}
+	}
+	if len(deferred) == 0 {
+		return
+	}
+	if dryRun || !isTerminal(os.Stdin) {
+		uuids := make([]string, len(deferred))
+		for i, t := range deferred {
+			uuids[i] = t.UUID
+		}
+		fmt.Fprintf(os.Stderr, "\nReview low-confidence tasks with: tg enrich %s\n", strings.Join(uuids, " "))
+		return
+	}
+
+	model := tui.NewEnrichReviewModel(cfg, provider, meter, deferred, enrichments)
+	p := tea.NewProgram(model, tea.WithAltScreen())
+
+	if _, err := p.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func runFocus() {
+	cfg := loadConfig()
 
 	model := tui.NewFocusModel(cfg)
 	p := tea.NewProgram(model)
@@ -112,6 +277,210 @@ func runFocus() {
 	}
 }
 
+func runCache() {
+	help := "Usage: tg cache stats | tg cache clear [--expired]"
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, help)
+		os.Exit(1)
+	}
+
+	cfg := loadConfig()
+
+	store, err := llm.OpenCache(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open cache: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch os.Args[2] {
+	case "stats":
+		stats, err := store.Stats()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read cache: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Directory: %s\n", stats.Dir)
+		fmt.Printf("Entries:   %d (%d expired)\n", stats.Entries, stats.Expired)
+		fmt.Printf("Size:      %.1f KiB\n", float64(stats.Bytes)/1024)
+		fmt.Printf("TTL:       %s\n", cfg.Cache.TTL)
+		if stats.Entries > 0 {
+			fmt.Printf("Oldest:    %s\n", stats.Oldest.Format("2006-01-02 15:04"))
+			fmt.Printf("Newest:    %s\n", stats.Newest.Format("2006-01-02 15:04"))
+		}
+	case "clear":
+		expiredOnly := len(os.Args) > 3 && os.Args[3] == "--expired"
+		removed, err := store.Clear(expiredOnly)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to clear cache: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed %d cached enrichments\n", removed)
+	default:
+		fmt.Fprintln(os.Stderr, help)
+		os.Exit(1)
+	}
+}
+
+func runPrompt() {
+	if len(os.Args) < 4 || os.Args[2] != "show" {
+		fmt.Fprintln(os.Stderr, "Usage: tg prompt show <description>")
+		os.Exit(1)
+	}
+
+	cfg := loadConfig()
+	prompt, err := llm.RenderPrompt(cfg, strings.Join(os.Args[3:], " "))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(prompt)
+}
+
+func runUndo() {
+	flags := flag.NewFlagSet("undo", flag.ExitOnError)
+	session := flags.String("session", "", "Session to undo (default: the most recent one)")
+	list := flags.Bool("list", false, "List journaled sessions instead of undoing")
+	flags.Parse(os.Args[2:])
+
+	if *list {
+		path, err := taskwarrior.DefaultJournalPath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to locate journal: %v\n", err)
+			os.Exit(1)
+		}
+		sessions, err := taskwarrior.OpenJournal(path).Sessions()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read journal: %v\n", err)
+			os.Exit(1)
+		}
+		if len(sessions) == 0 {
+			fmt.Println("No tg changes journaled")
+			return
+		}
+		for _, s := range sessions {
+			state := ""
+			if s.Undone {
+				state = "  (undone)"
+			}
+			fmt.Printf("%s  %s  %d added, %d modified, %d annotated%s\n",
+				s.ID, s.Started.Format("2006-01-02 15:04"), s.Added, s.Modified, s.Annotated, state)
+		}
+		return
+	}
+
+	report, err := taskwarrior.New().Undo(*session)
+	if err != nil && report == nil {
+		fmt.Fprintf(os.Stderr, "Undo failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Undid session %s: deleted %d tasks, restored %d tasks, removed %d annotations\n",
+		report.Session, len(report.Deleted), len(report.Restored), len(report.Denotated))
+	for _, e := range report.Errors {
+		fmt.Fprintf(os.Stderr, "  %v\n", e)
+	}
+	if len(report.Errors) > 0 {
+		fmt.Fprintf(os.Stderr, "The session stays open; run tg undo --session %s to retry the rest\n", report.Session)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to record undo: %v\n", err)
+	}
+	if err != nil || len(report.Errors) > 0 {
+		os.Exit(1)
+	}
+}
+
+func runLearn() {
+	flags := flag.NewFlagSet("learn", flag.ExitOnError)
+	minTimes := flags.Int("min", 3, "How often a correction must recur to become a preference")
+	dryRun := flags.Bool("dry-run", false, "Print the preferences without saving them")
+	flags.Parse(os.Args[2:])
+
+	logPath, err := feedback.DefaultLogPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to locate corrections log: %v\n", err)
+		os.Exit(1)
+	}
+	corrections, err := feedback.OpenLog(logPath).Corrections()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read corrections log: %v\n", err)
+		os.Exit(1)
+	}
+
+	changed := 0
+	for _, c := range corrections {
+		if c.Changed() {
+			changed++
+		}
+	}
+	fmt.Printf("%d reviewed enrichments, %d corrected\n", len(corrections), changed)
+
+	prefs := feedback.Learn(corrections, *minTimes)
+	if len(prefs) == 0 {
+		fmt.Println("No recurring corrections yet")
+	}
+	for _, p := range prefs {
+		fmt.Printf("  %s\n", p)
+	}
+	if *dryRun {
+		return
+	}
+
+	path, err := feedback.DefaultPreferencesPath()
+	if err == nil {
+		err = feedback.SavePreferences(path, prefs, len(corrections))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save preferences: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Saved to %s; future prompts include them\n", path)
+}
+
+func runUsage() {
+	flags := flag.NewFlagSet("usage", flag.ExitOnError)
+	days := flags.Int("days", 30, "How many days back to report, 0 for all")
+	flags.Parse(os.Args[2:])
+
+	path, err := usage.DefaultLogPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to locate usage log: %v\n", err)
+		os.Exit(1)
+	}
+	records, err := usage.OpenLog(path).Records()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read usage log: %v\n", err)
+		os.Exit(1)
+	}
+
+	var since time.Time
+	if *days > 0 {
+		since = time.Now().AddDate(0, 0, -*days)
+	}
+	summary := usage.Summarize(records, since)
+	if summary.Total.Calls == 0 {
+		fmt.Println("No LLM calls recorded")
+		return
+	}
+	usage.WriteReport(os.Stdout, summary)
+}
+
+// loadConfig loads the tg config and the UDA values declared in .taskrc, exiting on failure
+func loadConfig() *config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Enrichments are validated against .taskrc when task is available, config defaults otherwise
+	if values, err := taskwarrior.New().UDAValues(); err == nil {
+		cfg.ApplyTaskrcValues(values)
+	}
+
+	return cfg
+}
+
 func passthrough() {
 	// Pass all args to task command
 	args := os.Args[1:]
@@ -135,18 +504,52 @@ USAGE:
     tg <command> [arguments]
 
 COMMANDS:
-    add <description>    Add a new task with LLM enrichment
+    add [--split] <description>
+                         Add a new task with LLM enrichment
                          The LLM will suggest beacons, directions, project,
                          priority, and due date based on your goals
-
-    enrich [filter]      Batch enrich existing tasks
+                         --split         Break the task into dependent subtasks
+                                         under a tracking task (or press b in the preview)
+    add --file <path>, add -
+                         Add every item of a list (notes, an email, a markdown
+                         checklist) from a file or stdin, enriched in one batched
+                         request and reviewed in a table before they are added
+
+    enrich [options] [filter]
+                         Batch enrich existing tasks
                          Without filter: enriches all pending tasks without beacon tags
                          With filter: enriches tasks matching the taskwarrior filter
+                         --auto          Enrich in parallel without prompting; tasks below
+                                         the confidence threshold are reviewed afterwards
+                         --workers N     Parallel LLM calls in --auto mode
+                         --threshold F   Confidence (0-1) required to apply automatically
+                         --dry-run       Print the task modify commands instead of running them
+                         --budget F      Stop once the estimated LLM cost reaches F USD
 
     focus                Show balanced focus list across projects
                          Respects per-project quotas from config
                          Sorted by urgency within each project's quota
 
+    cache stats          Show the enrichment cache size and age
+    cache clear [--expired]
+                         Remove all cached enrichments, or only expired ones
+
+    prompt show <description>
+                         Print the prompt the LLM would get for a task, rendered
+                         from llm.prompt_template or the built-in template
+
+    learn [--min N] [--dry-run]
+                         Turn corrections you keep making in the preview and edit
+                         mode into preferences that future prompts include
+
+    usage [--days N]     Show tokens, latency and estimated cost of LLM calls by day,
+                         provider and command (default: the last 30 days)
+
+    undo [--session ID]  Revert the changes of the last tg session (or the given one):
+                         tasks tg added are deleted, fields it modified are restored
+    undo --list          List the sessions recorded in the undo journal
+                         (run task undo directly for taskwarrior's own undo)
+
     <any task command>   Passes through to taskwarrior
                          Example: tg list, tg done 5, tg project:work
 
@@ -155,7 +558,7 @@ CONFIGURATION:
 
     Example config:
         llm:
-          provider: anthropic  # or openai, ollama
+          provider: anthropic  # or openai, ollama, rules (offline)
           model: claude-sonnet-4-5-20250929
           api_key_env: ANTHROPIC_API_KEY
 
@@ -167,12 +570,15 @@ CONFIGURATION:
 
 ENVIRONMENT:
     Set your API key in the environment variable specified in config
-    (default: ANTHROPIC_API_KEY)
+    (default: ANTHROPIC_API_KEY). Without it, tg enriches from offline rules.
 
 EXAMPLES:
     tg add "Review PR for authentication changes"
+    tg add --split "Migrate CI from Jenkins to GitHub Actions"
+    pbpaste | tg add -
     tg enrich
     tg enrich project:work
+    tg enrich --auto --workers 8 +bugwarrior
     tg list +b.great.dev
 `
 	fmt.Print(help)
@@ -0,0 +1,177 @@
+// Package cache is a small on-disk key/value store with a TTL, used to avoid paying
+// for the same LLM call twice.
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Dir returns tg's cache directory, $XDG_CACHE_HOME/tg or the OS equivalent
+func Dir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get cache dir: %w", err)
+	}
+	return filepath.Join(dir, "tg"), nil
+}
+
+// Store keeps one JSON file per entry in a directory. Keys must be safe file names,
+// e.g. hex digests.
+type Store struct {
+	dir string
+	ttl time.Duration // 0 means entries never expire
+}
+
+type entry struct {
+	Created time.Time       `json:"created"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// Stats describes the contents of a Store
+type Stats struct {
+	Dir     string
+	Entries int
+	Expired int
+	Bytes   int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+func Open(dir string, ttl time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	return &Store{dir: dir, ttl: ttl}, nil
+}
+
+// Get loads the value stored under key into v. It reports false for missing and
+// expired entries; expired ones are removed.
+func (s *Store) Get(key string, v any) (bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		os.Remove(s.path(key))
+		return false, nil
+	}
+
+	if s.expired(e.Created) {
+		os.Remove(s.path(key))
+		return false, nil
+	}
+
+	if err := json.Unmarshal(e.Value, v); err != nil {
+		return false, fmt.Errorf("failed to decode cache entry: %w", err)
+	}
+	return true, nil
+}
+
+// Put stores v under key, replacing any previous value
+func (s *Store) Put(key string, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	data, err := json.Marshal(entry{Created: time.Now(), Value: value})
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	// Write then rename so concurrent readers never see a partial file
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmp.Name(), s.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Stats scans the store
+func (s *Store) Stats() (Stats, error) {
+	stats := Stats{Dir: s.dir}
+	err := s.each(func(path string, info fs.FileInfo, e entry) {
+		stats.Entries++
+		stats.Bytes += info.Size()
+		if s.expired(e.Created) {
+			stats.Expired++
+		}
+		if stats.Oldest.IsZero() || e.Created.Before(stats.Oldest) {
+			stats.Oldest = e.Created
+		}
+		if e.Created.After(stats.Newest) {
+			stats.Newest = e.Created
+		}
+	})
+	return stats, err
+}
+
+// Clear removes every entry, or only the expired ones, and returns how many were removed
+func (s *Store) Clear(expiredOnly bool) (int, error) {
+	removed := 0
+	err := s.each(func(path string, info fs.FileInfo, e entry) {
+		if expiredOnly && !s.expired(e.Created) {
+			return
+		}
+		if os.Remove(path) == nil {
+			removed++
+		}
+	})
+	return removed, err
+}
+
+func (s *Store) each(fn func(path string, info fs.FileInfo, e entry)) error {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache dir: %w", err)
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(s.dir, f.Name())
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// Unreadable entries keep a zero creation time, so any TTL treats them as expired
+		var e entry
+		json.Unmarshal(data, &e)
+		fn(path, info, e)
+	}
+	return nil
+}
+
+func (s *Store) expired(created time.Time) bool {
+	return s.ttl > 0 && time.Since(created) > s.ttl
+}
+
+func (s *Store) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
@@ -4,16 +4,209 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
 
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/spf13/viper"
 )
 
+const defaultCacheTTL = 30 * 24 * time.Hour
+
+const defaultFewShot = 5
+
+const defaultWorkers = 4
+
+const defaultConfidenceThreshold = 0.6
+
+const defaultLookahead = 2
+
+const defaultProviderTimeout = 60 * time.Second
+
 type Config struct {
 	LLM          LLMConfig    `mapstructure:"llm"`
 	Projects     []Project    `mapstructure:"projects"`
 	Beacons      []Beacon     `mapstructure:"beacons"`
 	FocusGroups  []FocusGroup `mapstructure:"focus_groups"`
 	DefaultQuota int          `mapstructure:"default_quota"` // Default tasks per project in focus list
+	UDAValues    UDAValues    `mapstructure:"uda_values"`    // Allowed values, overridden by .taskrc when available
+	Enrich       EnrichConfig `mapstructure:"enrich"`
+	Cache        CacheConfig  `mapstructure:"cache"`
+	Calendar     Calendar     `mapstructure:"calendar"`
+	Rules        RulesConfig  `mapstructure:"rules"`  // Offline enrichment without an LLM
+	Prices       []Price      `mapstructure:"prices"` // Model prices for the usage log, merged over built-in ones
+}
+
+// Price is what a model costs in USD per million tokens
+type Price struct {
+	Model  string  `mapstructure:"model"` // exact name, or a prefix such as "gpt-4o"
+	Input  float64 `mapstructure:"input"`
+	Output float64 `mapstructure:"output"`
+}
+
+// defaultPrices are list prices at the time of writing; configure prices to override them
+var defaultPrices = []Price{
+	{Model: "claude-opus-4", Input: 15, Output: 75},
+	{Model: "claude-sonnet-4", Input: 3, Output: 15},
+	{Model: "claude-3-7-sonnet", Input: 3, Output: 15},
+	{Model: "claude-3-5-sonnet", Input: 3, Output: 15},
+	{Model: "claude-haiku-4", Input: 1, Output: 5},
+	{Model: "claude-3-5-haiku", Input: 0.8, Output: 4},
+	{Model: "claude-3-haiku", Input: 0.25, Output: 1.25},
+	{Model: "gpt-4o-mini", Input: 0.15, Output: 0.6},
+	{Model: "gpt-4o", Input: 2.5, Output: 10},
+	{Model: "gpt-4.1-nano", Input: 0.1, Output: 0.4},
+	{Model: "gpt-4.1-mini", Input: 0.4, Output: 1.6},
+	{Model: "gpt-4.1", Input: 2, Output: 8},
+	{Model: "gpt-4-turbo", Input: 10, Output: 30},
+	{Model: "gpt-3.5-turbo", Input: 0.5, Output: 1.5},
+}
+
+// PriceOf returns the price of a model: an exact match first, otherwise the longest
+// configured prefix, so dated snapshots like gpt-4o-2024-08-06 are priced too.
+// Configured prices win over the built-in ones.
+func (c *Config) PriceOf(model string) (Price, bool) {
+	for _, prices := range [][]Price{c.Prices, defaultPrices} {
+		best := -1
+		for i, p := range prices {
+			if p.Model == model {
+				return p, true
+			}
+			if strings.HasPrefix(model, p.Model) && (best < 0 || len(p.Model) > len(prices[best].Model)) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			return prices[best], true
+		}
+	}
+	return Price{}, false
+}
+
+// RulesConfig drives the offline "rules" provider, used when no API key is set
+type RulesConfig struct {
+	Tags     []TagRule     `mapstructure:"tags"`
+	Estimate []KeywordRule `mapstructure:"estimate"` // first match wins, default a built-in table
+	Effort   []KeywordRule `mapstructure:"effort"`   // first match wins, default a built-in table
+}
+
+// TagRule adds beacon and direction tags to tasks whose description matches Pattern
+type TagRule struct {
+	Pattern    string   `mapstructure:"pattern"` // Go regular expression, e.g. (?i)\b(pr|review)\b
+	Beacons    []string `mapstructure:"beacons"`
+	Directions []string `mapstructure:"directions"`
+}
+
+// KeywordRule sets Value when any of Keywords appears as a word in the description
+type KeywordRule struct {
+	Keywords []string `mapstructure:"keywords"`
+	Value    string   `mapstructure:"value"`
+}
+
+func (r *RulesConfig) applyDefaults() {
+	if len(r.Estimate) == 0 {
+		r.Estimate = []KeywordRule{
+			{Keywords: []string{"typo", "reply", "email", "call", "ping", "remind", "pay", "book", "renew"}, Value: "15m"},
+			{Keywords: []string{"review", "check", "update", "schedule", "order", "buy", "read"}, Value: "30m"},
+			{Keywords: []string{"fix", "debug", "write", "prepare", "research", "investigate", "test"}, Value: "2h"},
+			{Keywords: []string{"implement", "design", "refactor", "migrate", "automate"}, Value: "4h"},
+			{Keywords: []string{"build", "rewrite", "launch", "plan"}, Value: "8h"},
+		}
+	}
+	if len(r.Effort) == 0 {
+		r.Effort = []KeywordRule{
+			{Keywords: []string{"design", "architecture", "refactor", "migrate", "debug", "investigate", "research", "rewrite"}, Value: "D"},
+			{Keywords: []string{"typo", "reply", "email", "call", "ping", "remind", "pay", "book", "buy", "renew", "update"}, Value: "E"},
+		}
+	}
+}
+
+// Calendar is the working calendar the LLM reasons about deadlines with
+type Calendar struct {
+	Timezone    string    `mapstructure:"timezone"`     // IANA name, default the system time zone
+	WorkingDays []string  `mapstructure:"working_days"` // mon..sun, default mon-fri
+	Holidays    []Holiday `mapstructure:"holidays"`
+}
+
+// Holiday is a non-working day
+type Holiday struct {
+	Date string `mapstructure:"date"` // YYYY-MM-DD
+	Name string `mapstructure:"name"`
+}
+
+// Location returns the configured time zone, or the system one when unset or unknown
+func (c Calendar) Location() *time.Location {
+	if c.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// defaultWorkingDays is used when calendar.working_days isn't configured
+var defaultWorkingDays = []string{"mon", "tue", "wed", "thu", "fri"}
+
+// Workdays returns the configured working days, mon-fri when unset
+func (c Calendar) Workdays() []string {
+	if len(c.WorkingDays) == 0 {
+		return defaultWorkingDays
+	}
+	return c.WorkingDays
+}
+
+// IsWorkingDay reports whether t falls on a working day that isn't a holiday
+func (c Calendar) IsWorkingDay(t time.Time) bool {
+	day := strings.ToLower(t.Weekday().String()[:3])
+	working := false
+	for _, d := range c.Workdays() {
+		if strings.HasPrefix(strings.ToLower(d), day) {
+			working = true
+			break
+		}
+	}
+	if !working {
+		return false
+	}
+	date := t.Format("2006-01-02")
+	for _, h := range c.Holidays {
+		if h.Date == date {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Calendar) applyDefaults() {
+	if len(c.WorkingDays) == 0 {
+		c.WorkingDays = defaultWorkingDays
+	}
+}
+
+// CacheConfig controls the on-disk enrichment cache
+type CacheConfig struct {
+	Disabled bool          `mapstructure:"disabled"`
+	TTL      time.Duration `mapstructure:"ttl"` // e.g. "720h", default 30 days
+}
+
+// EnrichConfig tunes `tg enrich`
+type EnrichConfig struct {
+	Workers             int     `mapstructure:"workers"`              // Parallel LLM calls in --auto mode, default 4
+	ConfidenceThreshold float64 `mapstructure:"confidence_threshold"` // Below this, --auto leaves the task for interactive review, default 0.6
+	Lookahead           int     `mapstructure:"lookahead"`            // Tasks enriched ahead of the one under review, default 2
+	Budget              float64 `mapstructure:"budget"`               // Estimated USD after which a batch stops, 0 for no limit
+}
+
+// UDAValues lists the allowed values for priority and tg's UDAs
+type UDAValues struct {
+	Priority []string `mapstructure:"priority"`
+	Effort   []string `mapstructure:"effort"`
+	Impact   []string `mapstructure:"impact"`
+	Estimate []string `mapstructure:"est"`
+	Fun      []string `mapstructure:"fun"`
 }
 
 type FocusGroup struct {
@@ -23,10 +216,59 @@ type FocusGroup struct {
 }
 
 type LLMConfig struct {
-	Provider  string `mapstructure:"provider"` // anthropic, openai, ollama
-	Model     string `mapstructure:"model"`
-	APIKeyEnv string `mapstructure:"api_key_env"`
-	BaseURL   string `mapstructure:"base_url"` // for ollama or custom endpoints
+	Provider       string            `mapstructure:"provider"` // anthropic, openai, ollama, rules
+	Model          string            `mapstructure:"model"`
+	APIKeyEnv      string            `mapstructure:"api_key_env"`
+	BaseURL        string            `mapstructure:"base_url"`        // for ollama or OpenAI-compatible endpoints
+	APIVersion     string            `mapstructure:"api_version"`     // api-version query parameter, e.g. for Azure OpenAI
+	Deployment     string            `mapstructure:"deployment"`      // Azure OpenAI deployment, replaces the model in the path
+	Headers        map[string]string `mapstructure:"headers"`         // extra request headers, e.g. OpenAI-Organization
+	MaxAttempts    int               `mapstructure:"max_attempts"`    // LLM calls per enrichment before giving up, default 3
+	PromptTemplate string            `mapstructure:"prompt_template"` // text/template file replacing the built-in prompt
+	FewShot        int               `mapstructure:"few_shot"`        // similar past tasks shown as examples, default 5 (0 disables)
+	NoStream       bool              `mapstructure:"no_stream"`       // wait for whole replies in tg add, for gateways that can't stream
+	Providers      []ProviderConfig  `mapstructure:"providers"`       // fallback chain, tried in order; replaces provider/model above
+}
+
+// ProviderConfig is one provider in the llm.providers fallback chain
+type ProviderConfig struct {
+	Provider   string            `mapstructure:"provider"` // anthropic, openai, ollama, rules
+	Model      string            `mapstructure:"model"`
+	APIKeyEnv  string            `mapstructure:"api_key_env"` // default ANTHROPIC_API_KEY or OPENAI_API_KEY
+	BaseURL    string            `mapstructure:"base_url"`
+	APIVersion string            `mapstructure:"api_version"`
+	Deployment string            `mapstructure:"deployment"`
+	Headers    map[string]string `mapstructure:"headers"`
+	Timeout    time.Duration     `mapstructure:"timeout"` // how long to wait before moving on to the next provider, default 60s
+}
+
+// ForProvider returns a copy of the config that talks to p; settings that aren't
+// per provider, like max_attempts or the prompt template, carry over
+func (c *Config) ForProvider(p ProviderConfig) *Config {
+	cfg := *c
+	cfg.LLM.Provider = p.Provider
+	cfg.LLM.Model = p.Model
+	cfg.LLM.APIKeyEnv = p.APIKeyEnv
+	cfg.LLM.BaseURL = p.BaseURL
+	cfg.LLM.APIVersion = p.APIVersion
+	cfg.LLM.Deployment = p.Deployment
+	cfg.LLM.Headers = p.Headers
+	cfg.LLM.Providers = nil
+	return &cfg
+}
+
+func (p *ProviderConfig) applyDefaults() {
+	if p.APIKeyEnv == "" {
+		switch p.Provider {
+		case "anthropic":
+			p.APIKeyEnv = "ANTHROPIC_API_KEY"
+		case "openai":
+			p.APIKeyEnv = "OPENAI_API_KEY"
+		}
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = defaultProviderTimeout
+	}
 }
 
 type Project struct {
@@ -48,6 +290,19 @@ type Direction struct {
 	Description string `mapstructure:"description"`
 }
 
+// decodeHook extends viper's default hooks so unquoted YAML dates, which parse as
+// timestamps, can fill string fields like Holiday.Date
+var decodeHook = viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+	func(from, to reflect.Type, data any) (any, error) {
+		if t, ok := data.(time.Time); ok && to.Kind() == reflect.String {
+			return t.Format("2006-01-02"), nil
+		}
+		return data, nil
+	},
+))
+
 func Load() (*Config, error) {
 	configDir, err := os.UserConfigDir()
 	if err != nil {
@@ -70,17 +325,25 @@ func Load() (*Config, error) {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
 			// Config file not found, use defaults + embedded beacons
 			cfg := &Config{}
-			if err := viper.Unmarshal(cfg); err != nil {
+			if err := viper.Unmarshal(cfg, decodeHook); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 			}
 			cfg.Beacons = DefaultBeacons()
+			cfg.UDAValues.applyDefaults()
+			cfg.Enrich.Workers = defaultWorkers
+			cfg.Enrich.ConfidenceThreshold = defaultConfidenceThreshold
+			cfg.Enrich.Lookahead = defaultLookahead
+			cfg.LLM.FewShot = defaultFewShot
+			cfg.Cache.TTL = defaultCacheTTL
+			cfg.Calendar.applyDefaults()
+			cfg.Rules.applyDefaults()
 			return cfg, nil
 		}
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
 	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
+	if err := viper.Unmarshal(&cfg, decodeHook); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
@@ -94,6 +357,14 @@ func Load() (*Config, error) {
 	if cfg.LLM.APIKeyEnv == "" {
 		cfg.LLM.APIKeyEnv = "ANTHROPIC_API_KEY"
 	}
+	if cfg.LLM.FewShot < 0 {
+		cfg.LLM.FewShot = 0
+	} else if !viper.IsSet("llm.few_shot") {
+		cfg.LLM.FewShot = defaultFewShot
+	}
+	for i := range cfg.LLM.Providers {
+		cfg.LLM.Providers[i].applyDefaults()
+	}
 
 	// Use default beacons if none configured
 	if len(cfg.Beacons) == 0 {
@@ -105,9 +376,43 @@ func Load() (*Config, error) {
 		cfg.DefaultQuota = 2
 	}
 
+	cfg.UDAValues.applyDefaults()
+	cfg.Rules.applyDefaults()
+
+	if cfg.Enrich.Workers <= 0 {
+		cfg.Enrich.Workers = defaultWorkers
+	}
+	if !viper.IsSet("enrich.confidence_threshold") {
+		cfg.Enrich.ConfidenceThreshold = defaultConfidenceThreshold
+	}
+	if cfg.Enrich.Lookahead < 0 {
+		cfg.Enrich.Lookahead = 0
+	} else if !viper.IsSet("enrich.lookahead") {
+		cfg.Enrich.Lookahead = defaultLookahead
+	}
+
+	if cfg.Cache.TTL == 0 {
+		cfg.Cache.TTL = defaultCacheTTL
+	}
+
+	cfg.Calendar.applyDefaults()
+
 	return &cfg, nil
 }
 
+// StateDir returns the directory for tg's local records (journal, logs),
+// $XDG_STATE_HOME/tg or ~/.local/state/tg
+func StateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "tg"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home dir: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "tg"), nil
+}
+
 func (c *Config) GetAPIKey() string {
 	if c.LLM.APIKeyEnv == "" {
 		return ""
@@ -115,6 +420,47 @@ func (c *Config) GetAPIKey() string {
 	return os.Getenv(c.LLM.APIKeyEnv)
 }
 
+// ApplyTaskrcValues overrides the allowed UDA values with the ones declared in .taskrc
+// (uda.<name>.values), keyed by UDA name as returned by taskwarrior.Client.UDAValues
+func (c *Config) ApplyTaskrcValues(values map[string][]string) {
+	fields := map[string]*[]string{
+		"priority": &c.UDAValues.Priority,
+		"effort":   &c.UDAValues.Effort,
+		"impact":   &c.UDAValues.Impact,
+		"est":      &c.UDAValues.Estimate,
+		"fun":      &c.UDAValues.Fun,
+	}
+	for name, field := range fields {
+		var allowed []string
+		for _, v := range values[name] {
+			if v != "" {
+				allowed = append(allowed, v)
+			}
+		}
+		if len(allowed) > 0 {
+			*field = allowed
+		}
+	}
+}
+
+func (u *UDAValues) applyDefaults() {
+	if len(u.Priority) == 0 {
+		u.Priority = []string{"H", "M", "L"}
+	}
+	if len(u.Effort) == 0 {
+		u.Effort = []string{"E", "N", "D"}
+	}
+	if len(u.Impact) == 0 {
+		u.Impact = []string{"H", "M", "L"}
+	}
+	if len(u.Estimate) == 0 {
+		u.Estimate = []string{"15m", "30m", "1h", "2h", "4h", "8h", "2d"}
+	}
+	if len(u.Fun) == 0 {
+		u.Fun = []string{"H", "M", "L"}
+	}
+}
+
 // GetProjectQuota returns the quota for a specific project, or default if not set
 func (c *Config) GetProjectQuota(projectName string) int {
 	for _, p := range c.Projects {
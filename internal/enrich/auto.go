@@ -0,0 +1,273 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+
+	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/llm"
+	"github.com/bf/tg/internal/taskwarrior"
+	"github.com/bf/tg/internal/usage"
+)
+
+// Outcome is what an automatic run did with a task
+type Outcome int
+
+const (
+	Applied  Outcome = iota // modified in Taskwarrior
+	Planned                 // modify command recorded (dry run)
+	Deferred                // low confidence or an invalid date, left for interactive review
+	Failed                  // enrichment or modify failed
+	Skipped                 // not enriched because the budget ran out
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Applied:
+		return "applied"
+	case Planned:
+		return "planned"
+	case Deferred:
+		return "review"
+	case Skipped:
+		return "skipped"
+	default:
+		return "failed"
+	}
+}
+
+// Result is the record of one task in an automatic run
+type Result struct {
+	Task       taskwarrior.Task
+	Enrichment *llm.Enrichment
+	Fixes      []llm.Fix
+	Outcome    Outcome
+	Command    string // task modify command line, set for Applied and Planned
+	Err        error
+}
+
+// Auto enriches many tasks without interaction. LLM calls run on a bounded worker
+// pool; modifications are applied one at a time since task locks its data files.
+type Auto struct {
+	cfg       *config.Config
+	provider  llm.Provider
+	meter     *llm.Meter // the provider's, for the budget and the cost so far
+	twClient  *taskwarrior.Client
+	Workers   int
+	Threshold float64   // enrichments below this confidence are deferred
+	Budget    float64   // estimated USD after which no more tasks are sent to the LLM, 0 for no limit
+	DryRun    bool      // record the modify commands instead of running them
+	Progress  io.Writer // receives one line per finished task, nil for silence
+}
+
+func NewAuto(cfg *config.Config, provider llm.Provider, meter *llm.Meter) *Auto {
+	return &Auto{
+		cfg:       cfg,
+		provider:  provider,
+		meter:     meter,
+		twClient:  taskwarrior.New(),
+		Workers:   cfg.Enrich.Workers,
+		Threshold: cfg.Enrich.ConfidenceThreshold,
+		Budget:    cfg.Enrich.Budget,
+	}
+}
+
+type job struct {
+	index int
+	task  taskwarrior.Task
+}
+
+type jobResult struct {
+	index int
+	Result
+}
+
+// ErrBudget is the error of tasks skipped because the budget ran out
+var ErrBudget = errors.New("budget reached")
+
+// Run enriches tasks and returns one Result per task, in the order given.
+// Tasks not reached before ctx is cancelled are reported as failed, those not
+// reached before the budget ran out as skipped. Calls already in flight when the
+// budget runs out still finish, so the spend can overshoot it slightly.
+func (a *Auto) Run(ctx context.Context, tasks []taskwarrior.Task) []Result {
+	jobs := make(chan job)
+	done := make(chan jobResult)
+	var overBudget atomic.Bool
+
+	var wg sync.WaitGroup
+	for range max(a.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				done <- jobResult{index: j.index, Result: a.enrich(ctx, j.task)}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for i, task := range tasks {
+			if a.Budget > 0 && a.meter.Spent() >= a.Budget {
+				overBudget.Store(true)
+				return
+			}
+			select {
+			case jobs <- job{index: i, task: task}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	results := make([]Result, len(tasks))
+	for i, task := range tasks {
+		results[i] = Result{Task: task, Outcome: Failed, Err: context.Canceled}
+	}
+
+	finished := 0
+	reached := make([]bool, len(tasks))
+	for r := range done {
+		results[r.index] = a.apply(r.Result)
+		reached[r.index] = true
+		finished++
+		if a.Progress != nil {
+			res := results[r.index]
+			fmt.Fprintf(a.Progress, "[%d/%d] %-7s %s  %s\n", finished, len(tasks), res.Outcome,
+				usage.FormatCost(a.meter.Spent()), truncate(res.Task.Description, 60))
+		}
+	}
+
+	if overBudget.Load() {
+		for i := range results {
+			if !reached[i] {
+				results[i].Outcome, results[i].Err = Skipped, ErrBudget
+			}
+		}
+		if a.Progress != nil {
+			fmt.Fprintf(a.Progress, "Stopped at the budget of %s\n", usage.FormatCost(a.Budget))
+		}
+	}
+	return results
+}
+
+// enrich runs on a worker: it calls the LLM and validates, but changes nothing
+func (a *Auto) enrich(ctx context.Context, task taskwarrior.Task) Result {
+	r := Result{Task: task}
+
+	enrichment, err := a.provider.Enrich(ctx, task, a.cfg.Beacons, a.cfg.Projects)
+	if err != nil {
+		r.Outcome, r.Err = Failed, err
+		return r
+	}
+	r.Enrichment = enrichment
+	r.Fixes = llm.Validate(enrichment, a.cfg)
+
+	if enrichment.Confidence < a.Threshold {
+		r.Outcome = Deferred
+	}
+	return r
+}
+
+// apply runs on the collecting goroutine so the task modify and calc calls it makes,
+// including those resolving dates, never run concurrently
+func (a *Auto) apply(r Result) Result {
+	if r.Err != nil {
+		return r
+	}
+
+	// A date Taskwarrior can't parse is left for review rather than failing the modify
+	for _, d := range []struct{ field, expr string }{{"due", r.Enrichment.Due}, {"scheduled", r.Enrichment.Scheduled}} {
+		if d.expr == "" {
+			continue
+		}
+		if _, err := a.twClient.ResolveDate(d.expr); err != nil {
+			r.Fixes = append(r.Fixes, llm.Fix{Field: d.field, From: d.expr, Note: "is not a valid date"})
+			r.Outcome = Deferred
+		}
+	}
+	if r.Outcome == Deferred {
+		return r
+	}
+
+	modified := Modification(r.Task, r.Enrichment)
+	args, err := a.twClient.ModifyArgs(r.Task.UUID, modified)
+	if err != nil {
+		r.Outcome, r.Err = Failed, err
+		return r
+	}
+	r.Command = taskwarrior.CommandLine(args)
+
+	if a.DryRun {
+		r.Outcome = Planned
+		return r
+	}
+
+	if err := a.twClient.Modify(r.Task.UUID, modified); err != nil {
+		r.Outcome, r.Err = Failed, err
+		return r
+	}
+	r.Outcome = Applied
+	return r
+}
+
+// WritePlan writes the modify commands of a dry run, one per line, ready for a shell
+func WritePlan(w io.Writer, results []Result) {
+	for _, r := range results {
+		if r.Outcome == Planned {
+			fmt.Fprintln(w, r.Command)
+		}
+	}
+}
+
+// WriteSummary writes a table of every task's outcome followed by the totals and
+// spent, the estimated cost of the run
+func WriteSummary(w io.Writer, results []Result, spent float64) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tCONF\tTAGS\tDESCRIPTION")
+
+	counts := make(map[Outcome]int)
+	for _, r := range results {
+		counts[r.Outcome]++
+
+		conf, tags := "--", ""
+		if r.Enrichment != nil {
+			conf = fmt.Sprintf("%.2f", r.Enrichment.Confidence)
+			tags = strings.Join(append(append([]string{}, r.Enrichment.Beacons...), r.Enrichment.Directions...), " ")
+		}
+		desc := truncate(r.Task.Description, 50)
+		if r.Err != nil {
+			desc += " (" + truncate(firstLine(r.Err.Error()), 60) + ")"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", r.Task.ID, r.Outcome, conf, tags, desc)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nApplied: %d  Planned: %d  Review: %d  Failed: %d  Skipped: %d\n",
+		counts[Applied], counts[Planned], counts[Deferred], counts[Failed], counts[Skipped])
+	fmt.Fprintf(w, "Estimated LLM cost: %s\n", usage.FormatCost(spent))
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
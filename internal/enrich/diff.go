@@ -0,0 +1,76 @@
+package enrich
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bf/tg/internal/taskwarrior"
+)
+
+// ChangeKind classifies one field of a modification
+type ChangeKind int
+
+const (
+	Unchanged   ChangeKind = iota // nothing proposed, or the same value
+	Added                         // field was empty
+	Overwritten                   // field had a different value
+)
+
+// Change is one row of the diff between a task and the modification applied to it
+type Change struct {
+	Field    string
+	Current  string
+	Proposed string
+	Kind     ChangeKind
+}
+
+// Diff compares an existing task with the modification Client.Modify would apply.
+// Modify never clears fields or removes tags, so an empty proposal means the current
+// value stays, and the tags row lists only the tags that would be added.
+func Diff(task taskwarrior.Task, modified *taskwarrior.Task) []Change {
+	changes := []Change{
+		fieldChange("Project", task.Project, modified.Project),
+		fieldChange("Priority", task.Priority, modified.Priority),
+		fieldChange("Due", taskwarrior.FormatDate(task.Due), modified.Due),
+		fieldChange("Scheduled", taskwarrior.FormatDate(task.Scheduled), modified.Scheduled),
+		fieldChange("Effort", task.Effort, modified.Effort),
+		fieldChange("Impact", task.Impact, modified.Impact),
+		fieldChange("Estimate", task.Estimate, modified.Estimate),
+		fieldChange("Fun", task.Fun, modified.Fun),
+		fieldChange("Blocks", blocksString(task.Blocks), blocksString(modified.Blocks)),
+	}
+
+	var added []string
+	for _, tag := range modified.Tags {
+		if !slices.Contains(task.Tags, tag) && !slices.Contains(added, tag) {
+			added = append(added, tag)
+		}
+	}
+	tags := Change{Field: "Tags", Current: strings.Join(task.Tags, " "), Proposed: strings.Join(added, " ")}
+	if len(added) > 0 {
+		tags.Kind = Added
+	}
+
+	return append(changes, tags)
+}
+
+func fieldChange(field, current, proposed string) Change {
+	c := Change{Field: field, Current: current, Proposed: proposed}
+	switch {
+	case proposed == "" || proposed == current:
+		c.Kind = Unchanged
+	case current == "":
+		c.Kind = Added
+	default:
+		c.Kind = Overwritten
+	}
+	return c
+}
+
+func blocksString(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,39 @@
+// Package enrich applies LLM enrichments to existing Taskwarrior tasks, either one at a
+// time from the TUI or in bulk without interaction.
+package enrich
+
+import (
+	"github.com/bf/tg/internal/llm"
+	"github.com/bf/tg/internal/taskwarrior"
+)
+
+// Modification turns an accepted enrichment into the changes to apply to an existing task.
+// The description is never modified by Client.Modify and an existing project is preserved.
+func Modification(task taskwarrior.Task, e *llm.Enrichment) *taskwarrior.Task {
+	modified := &taskwarrior.Task{
+		Description: e.Description,
+		Priority:    e.Priority,
+		Due:         e.Due,
+		Scheduled:   e.Scheduled,
+		Effort:      e.Effort,
+		Impact:      e.Impact,
+		Estimate:    e.Estimate,
+		Fun:         e.Fun,
+		Blocks:      e.Blocks,
+	}
+
+	// Only set project if task doesn't already have one
+	// This preserves bugwarrior-synced projects and prevents accidental overwrites
+	if task.Project == "" {
+		modified.Project = e.Project
+	}
+
+	modified.Tags = append(modified.Tags, e.Beacons...)
+	modified.Tags = append(modified.Tags, e.Directions...)
+
+	if e.IsWaste {
+		modified.Tags = append(modified.Tags, "waste")
+	}
+
+	return modified
+}
@@ -0,0 +1,120 @@
+// Package feedback records how the user corrects enrichment suggestions and turns
+// recurring corrections into preferences that future prompts pass on to the model.
+package feedback
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/bf/tg/internal/config"
+)
+
+// Values are the enrichment fields corrections are tracked for. Descriptions and
+// dates are left out: rewrites and deadlines are specific to each task.
+type Values struct {
+	Beacons    []string `json:"beacons,omitempty"`
+	Directions []string `json:"directions,omitempty"`
+	Project    string   `json:"project,omitempty"`
+	Priority   string   `json:"priority,omitempty"`
+	Effort     string   `json:"effort,omitempty"`
+	Impact     string   `json:"impact,omitempty"`
+	Estimate   string   `json:"estimate,omitempty"`
+	Fun        string   `json:"fun,omitempty"`
+	Blocks     int      `json:"blocks,omitempty"`
+}
+
+// Correction is one reviewed enrichment: what the model suggested and what the user
+// accepted. Reviews where nothing changed are recorded too, so tg learn can tell a
+// habit from a one-off.
+type Correction struct {
+	Time        time.Time `json:"time"`
+	Description string    `json:"description"` // the task as the user wrote it
+	Suggested   Values    `json:"suggested"`
+	Final       Values    `json:"final"`
+}
+
+// Changed reports whether the user changed anything about the suggestion
+func (c Correction) Changed() bool {
+	s, f := c.Suggested, c.Final
+	return !slices.Equal(s.Beacons, f.Beacons) || !slices.Equal(s.Directions, f.Directions) ||
+		s.Project != f.Project || s.Priority != f.Priority || s.Effort != f.Effort ||
+		s.Impact != f.Impact || s.Estimate != f.Estimate || s.Fun != f.Fun || s.Blocks != f.Blocks
+}
+
+// Log is an append-only JSONL file of corrections
+type Log struct {
+	path string
+}
+
+func OpenLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// DefaultLogPath returns corrections.jsonl in tg's state directory
+func DefaultLogPath() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "corrections.jsonl"), nil
+}
+
+// Append writes a correction, stamping it with the current time if unset
+func (l *Log) Append(c Correction) error {
+	if c.Time.IsZero() {
+		c.Time = time.Now()
+	}
+
+	line, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode correction: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create corrections dir: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open corrections log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write corrections log: %w", err)
+	}
+	return nil
+}
+
+// Corrections reads the whole log in the order it was written
+func (l *Log) Corrections() ([]Correction, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corrections log: %w", err)
+	}
+	defer f.Close()
+
+	var corrections []Correction
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var c Correction
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			continue // skip a torn line rather than lose the whole log
+		}
+		corrections = append(corrections, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read corrections log: %w", err)
+	}
+	return corrections, nil
+}
@@ -0,0 +1,234 @@
+package feedback
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bf/tg/internal/config"
+)
+
+// minShare is how consistently a correction has to be made to count as a habit
+const minShare = 0.6
+
+// ticketKey matches issue keys such as JIRA-123 in descriptions
+var ticketKey = regexp.MustCompile(`\b([A-Z][A-Z0-9]+)-\d+\b`)
+
+// Preference is a correction the user keeps making, e.g. lowering fun on JIRA tickets
+type Preference struct {
+	Context string // the tasks it applies to, e.g. "JIRA- tasks"; empty for all tasks
+	Field   string
+	From    string // the suggested value or tag; empty when the user adds a tag
+	To      string // the value or tag the user wanted; empty when they dropped it
+	Times   int    // how often the user made this correction
+	Of      int    // out of how many reviews it could have applied to
+}
+
+// String phrases the preference as an instruction for the model
+func (p Preference) String() string {
+	var text string
+	switch {
+	case p.Field == "beacons" || p.Field == "directions":
+		kind := strings.TrimSuffix(p.Field, "s")
+		if p.From != "" {
+			text = fmt.Sprintf("don't suggest the %s tag %s", kind, p.From)
+		} else {
+			text = fmt.Sprintf("add the %s tag %s", kind, p.To)
+		}
+	case p.From == "":
+		text = fmt.Sprintf("set %s to %s", p.Field, p.To)
+	case p.To == "":
+		text = fmt.Sprintf("leave %s empty rather than %s", p.Field, p.From)
+	default:
+		text = fmt.Sprintf("use %s %s rather than %s", p.Field, p.To, p.From)
+	}
+
+	if p.Context != "" {
+		text = "for " + p.Context + ", " + text
+	}
+	return strings.ToUpper(text[:1]) + text[1:] + fmt.Sprintf(" (corrected %d of %d times)", p.Times, p.Of)
+}
+
+// rule identifies a correction within a group of tasks; with To unset it counts the
+// reviews the correction could have been made in
+type rule struct {
+	context, field, from, to string
+}
+
+// Learn finds the corrections made at least minTimes and in most of the reviews
+// they could apply to, for all tasks, per project and per ticket prefix. A habit that
+// holds for all tasks isn't repeated for each group.
+func Learn(corrections []Correction, minTimes int) []Preference {
+	opportunities := make(map[rule]int)
+	outcomes := make(map[rule]int)
+
+	for _, c := range corrections {
+		for _, context := range contexts(c) {
+			for _, f := range fields {
+				from, to := f.get(c.Suggested), f.get(c.Final)
+				opportunities[rule{context, f.name, from, ""}]++
+				if from != to {
+					outcomes[rule{context, f.name, from, to}]++
+				}
+			}
+
+			for _, f := range tagFields {
+				suggested, final := f.get(c.Suggested), f.get(c.Final)
+				// Every review is a chance to add a tag
+				opportunities[rule{context, f.name, "", ""}]++
+				for _, tag := range suggested {
+					opportunities[rule{context, f.name, tag, ""}]++
+					if !slices.Contains(final, tag) {
+						outcomes[rule{context, f.name, tag, ""}]++
+					}
+				}
+				for _, tag := range final {
+					if !slices.Contains(suggested, tag) {
+						outcomes[rule{context, f.name, "", tag}]++
+					}
+				}
+			}
+		}
+	}
+
+	habit := func(r rule) bool {
+		n, of := outcomes[r], opportunities[rule{r.context, r.field, r.from, ""}]
+		return n >= minTimes && of > 0 && float64(n)/float64(of) >= minShare
+	}
+
+	var prefs []Preference
+	for r, n := range outcomes {
+		if !habit(r) {
+			continue
+		}
+		if r.context != "" && habit(rule{"", r.field, r.from, r.to}) {
+			continue
+		}
+		prefs = append(prefs, Preference{
+			Context: describeContext(r.context),
+			Field:   r.field,
+			From:    r.from,
+			To:      r.to,
+			Times:   n,
+			Of:      opportunities[rule{r.context, r.field, r.from, ""}],
+		})
+	}
+
+	slices.SortFunc(prefs, func(a, b Preference) int {
+		return cmp.Or(
+			cmp.Compare(a.Context, b.Context),
+			cmp.Compare(b.Times, a.Times),
+			cmp.Compare(a.Field, b.Field),
+			cmp.Compare(a.From+a.To, b.From+b.To),
+		)
+	})
+	return prefs
+}
+
+var fields = []struct {
+	name string
+	get  func(Values) string
+}{
+	{"project", func(v Values) string { return v.Project }},
+	{"priority", func(v Values) string { return v.Priority }},
+	{"effort", func(v Values) string { return v.Effort }},
+	{"impact", func(v Values) string { return v.Impact }},
+	{"estimate", func(v Values) string { return v.Estimate }},
+	{"fun", func(v Values) string { return v.Fun }},
+	{"blocks", func(v Values) string { return strconv.Itoa(v.Blocks) }},
+}
+
+var tagFields = []struct {
+	name string
+	get  func(Values) []string
+}{
+	{"beacons", func(v Values) []string { return v.Beacons }},
+	{"directions", func(v Values) []string { return v.Directions }},
+}
+
+// contexts returns the groups a review counts towards: all tasks, the project the
+// task ended up in and the prefix of each ticket key in its description
+func contexts(c Correction) []string {
+	groups := []string{""}
+	if c.Final.Project != "" {
+		groups = append(groups, "project:"+c.Final.Project)
+	}
+	for _, m := range ticketKey.FindAllStringSubmatch(c.Description, -1) {
+		if group := "ticket:" + m[1]; !slices.Contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+func describeContext(context string) string {
+	if project, ok := strings.CutPrefix(context, "project:"); ok {
+		return "tasks in project " + project
+	}
+	if prefix, ok := strings.CutPrefix(context, "ticket:"); ok {
+		return prefix + "- tasks"
+	}
+	return context
+}
+
+// DefaultPreferencesPath returns preferences.txt in tg's state directory
+func DefaultPreferencesPath() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "preferences.txt"), nil
+}
+
+// SavePreferences writes one preference per line, replacing the file
+func SavePreferences(path string, prefs []Preference, reviews int) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Written by tg learn on %s from %d reviews. Lines starting with # are ignored;\n", time.Now().Format("2006-01-02"), reviews)
+	sb.WriteString("# edit freely, but rerunning tg learn replaces this file.\n")
+	for _, p := range prefs {
+		sb.WriteString(p.String() + "\n")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create preferences dir: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write preferences: %w", err)
+	}
+	return nil
+}
+
+// LoadPreferences reads the preferences to put in the prompt; a missing file means none
+func LoadPreferences(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open preferences: %w", err)
+	}
+	defer f.Close()
+
+	var prefs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		prefs = append(prefs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read preferences: %w", err)
+	}
+	return prefs, nil
+}
@@ -0,0 +1,98 @@
+// Package inbox turns a pasted list, such as notes, an email or a markdown checklist,
+// into task descriptions for tg add --file.
+package inbox
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// bullet matches list markers: -, *, +, •, numbers like 1. or 2), and markdown
+// checkboxes, optionally after a marker
+var bullet = regexp.MustCompile(`^(?:[-*+•–]\s+|\d{1,3}[.)]\s+)?\[([ xX]?)\]\s*|^(?:[-*+•–]|\d{1,3}[.)])\s+`)
+
+// Parse reads one task per list item. Blank lines, markdown headings, separators and
+// checked checkboxes (done already) are skipped, and email quote markers are
+// removed. In a bulleted list a line without a marker is a heading when it ends in a
+// colon, and continues the item above when it is indented. Repeated items are kept once.
+func Parse(r io.Reader) ([]string, error) {
+	type line struct {
+		text     string
+		indented bool
+		bulleted bool
+		done     bool
+	}
+
+	var lines []line
+	anyBullet := false
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		raw := strings.TrimRightFunc(scanner.Text(), unicode.IsSpace)
+		for strings.HasPrefix(strings.TrimLeft(raw, " \t"), ">") {
+			raw = strings.TrimPrefix(strings.TrimLeft(raw, " \t"), ">")
+			raw = strings.TrimPrefix(raw, " ")
+		}
+
+		text := strings.TrimSpace(raw)
+		if text == "" || strings.HasPrefix(text, "#") || isSeparator(text) {
+			lines = append(lines, line{})
+			continue
+		}
+
+		l := line{indented: raw[0] == ' ' || raw[0] == '\t'}
+		if m := bullet.FindStringSubmatch(text); m != nil {
+			l.bulleted = true
+			l.done = strings.EqualFold(m[1], "x")
+			text = strings.TrimSpace(text[len(m[0]):])
+			anyBullet = true
+		}
+		l.text = text
+		lines = append(lines, l)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var items []string
+	seen := make(map[string]bool)
+	current := -1 // the item a continuation line extends, -1 when there is none
+	add := func(text string) {
+		key := strings.ToLower(text)
+		if text == "" || seen[key] {
+			current = -1
+			return
+		}
+		seen[key] = true
+		items = append(items, text)
+		current = len(items) - 1
+	}
+
+	for _, l := range lines {
+		switch {
+		case l.text == "":
+			current = -1
+		case l.done:
+			current = -1
+		case l.bulleted || !anyBullet:
+			add(l.text)
+		case l.indented && current >= 0:
+			delete(seen, strings.ToLower(items[current]))
+			items[current] += " " + l.text
+			seen[strings.ToLower(items[current])] = true
+		case strings.HasSuffix(l.text, ":"):
+			current = -1
+		default:
+			add(l.text)
+		}
+	}
+	return items, nil
+}
+
+// isSeparator reports lines like --- or === that only divide sections
+func isSeparator(s string) bool {
+	return len(s) >= 3 && strings.Trim(s, "-=*_~ ") == ""
+}
@@ -0,0 +1,102 @@
+package inbox
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{
+			name: "plain lines",
+			in:   "Call Bob\n\nRenew passport\n  Book flights  \n",
+			want: []string{"Call Bob", "Renew passport", "Book flights"},
+		},
+		{
+			name: "headings and separators",
+			in:   "# Today\nCall Bob\n---\n=== \n## Later\nRenew passport\n",
+			want: []string{"Call Bob", "Renew passport"},
+		},
+		{
+			name: "bullets",
+			in:   "- dash\n* star\n+ plus\n• dot\n– en dash\n1. numbered\n12) parenthesis\n",
+			want: []string{"dash", "star", "plus", "dot", "en dash", "numbered", "parenthesis"},
+		},
+		{
+			name: "not bullets",
+			in:   "-5 degrees outside\n*bold* move\n2024 taxes\n",
+			want: []string{"-5 degrees outside", "*bold* move", "2024 taxes"},
+		},
+		{
+			name: "checkboxes",
+			in:   "- [ ] open\n- [x] done\n* [X] done too\n[ ] bare\n[] empty\n1. [ ] numbered\n- [ ]no space\n",
+			want: []string{"open", "bare", "empty", "numbered", "no space"},
+		},
+		{
+			name: "email quotes",
+			in:   "> - quoted\n>> - nested\n > - indented quote\n>\n>- no space\n",
+			want: []string{"quoted", "nested", "indented quote", "no space"},
+		},
+		{
+			name: "continuation lines",
+			in:   "- Write report\n  with the Q3 numbers\n\tand charts\n- Call Bob\n",
+			want: []string{"Write report with the Q3 numbers and charts", "Call Bob"},
+		},
+		{
+			name: "quoted continuation",
+			in:   "> - Write report\n>   with the Q3 numbers\n",
+			want: []string{"Write report with the Q3 numbers"},
+		},
+		{
+			name: "colon headings in a bulleted list",
+			in:   "Groceries:\n- milk\nWork:\n  - deploy\n",
+			want: []string{"milk", "deploy"},
+		},
+		{
+			name: "unindented line in a bulleted list",
+			in:   "- milk\nfix the bike\n  before Sunday\n",
+			want: []string{"milk", "fix the bike before Sunday"},
+		},
+		{
+			name: "indented line after a blank",
+			in:   "- milk\n\n  fix the bike\n",
+			want: []string{"milk", "fix the bike"},
+		},
+		{
+			name: "continuation of a done item",
+			in:   "- [x] milk\n  and eggs\n- bread\n",
+			want: []string{"and eggs", "bread"},
+		},
+		{
+			name: "colon without bullets",
+			in:   "Note to self:\nCall Bob\n",
+			want: []string{"Note to self:", "Call Bob"},
+		},
+		{
+			name: "repeats",
+			in:   "- Call Bob\n- call bob\n- Write report\n  today\n- write report today\n",
+			want: []string{"Call Bob", "Write report today"},
+		},
+		{
+			name: "empty",
+			in:   "\n\n# Nothing here\n---\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Parse(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
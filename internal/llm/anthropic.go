@@ -2,19 +2,25 @@ package llm
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/taskwarrior"
 )
 
 type Anthropic struct {
 	apiKey string
 	model  string
 	client *http.Client
+	retry  retryPolicy
+	prompt promptBuilder
 }
 
 func NewAnthropic(apiKey, model string) *Anthropic {
@@ -25,38 +31,125 @@ func NewAnthropic(apiKey, model string) *Anthropic {
 		apiKey: apiKey,
 		model:  model,
 		client: &http.Client{},
+		retry:  retryPolicy{maxAttempts: defaultMaxAttempts},
 	}
 }
 
 type anthropicRequest struct {
-	Model     string              `json:"model"`
-	MaxTokens int                 `json:"max_tokens"`
-	Messages  []anthropicMessage  `json:"messages"`
+	Model      string               `json:"model"`
+	MaxTokens  int                  `json:"max_tokens"`
+	Messages   []anthropicMessage   `json:"messages"`
+	Tools      []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Stream     bool                 `json:"stream,omitempty"`
 }
 
 type anthropicMessage struct {
 	Role    string `json:"role"`
-	Content string `json:"content"`
+	Content any    `json:"content"` // string or []anthropicBlock
+}
+
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
 }
 
 type anthropicResponse struct {
-	Content []struct {
-		Text string `json:"text"`
-	} `json:"content"`
+	Model   string           `json:"model"`
+	Content []anthropicBlock `json:"content"`
+	Usage   struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
 	Error *struct {
 		Message string `json:"message"`
 	} `json:"error"`
 }
 
-func (a *Anthropic) Enrich(ctx context.Context, taskDesc string, beacons []config.Beacon, projects []config.Project) (*Enrichment, error) {
-	prompt := buildPrompt(taskDesc, beacons, projects)
+// anthropicEvent is one server-sent event of a streamed reply
+type anthropicEvent struct {
+	Type         string             `json:"type"`
+	Index        int                `json:"index"`
+	Message      *anthropicResponse `json:"message"`       // message_start
+	ContentBlock *anthropicBlock    `json:"content_block"` // content_block_start
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Usage *struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"` // message_delta
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
 
+func (a *Anthropic) Enrich(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Enrichment, error) {
+	prompt, err := a.prompt.build(task, beacons, projects)
+	if err != nil {
+		return nil, err
+	}
+	return a.retry.enrich(ctx, a, prompt)
+}
+
+func (a *Anthropic) EnrichBatch(ctx context.Context, tasks []taskwarrior.Task, beacons []config.Beacon, projects []config.Project) ([]*Enrichment, error) {
+	return a.retry.enrichBatch(ctx, a, a.prompt, tasks, beacons, projects)
+}
+
+func (a *Anthropic) Split(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Split, error) {
+	prompt, err := a.prompt.buildSplit(task, beacons, projects)
+	if err != nil {
+		return nil, err
+	}
+	return a.retry.split(ctx, a, prompt)
+}
+
+func (a *Anthropic) name() string {
+	return "anthropic"
+}
+
+func (a *Anthropic) complete(ctx context.Context, prompt string, t tool, history []turn) (*completion, error) {
+	stream := newPartialStream(ctx)
+	messages := []anthropicMessage{
+		{Role: "user", Content: prompt},
+	}
+	for _, h := range history {
+		messages = append(messages, a.feedbackMessages(t, h)...)
+	}
+
+	// Force a call to the tool so the reply is structured by the API
 	reqBody := anthropicRequest{
 		Model:     a.model,
-		MaxTokens: 1024,
-		Messages: []anthropicMessage{
-			{Role: "user", Content: prompt},
+		MaxTokens: t.maxTokens,
+		Messages:  messages,
+		Tools: []anthropicTool{
+			{
+				Name:        t.name,
+				Description: t.description,
+				InputSchema: t.schema,
+			},
 		},
+		ToolChoice: &anthropicToolChoice{Type: "tool", Name: t.name},
+		Stream:     stream != nil,
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -79,6 +172,10 @@ func (a *Anthropic) Enrich(ctx context.Context, taskDesc string, beacons []confi
 	}
 	defer resp.Body.Close()
 
+	if stream != nil && resp.StatusCode < 400 {
+		return a.readStream(resp.Body, t, stream)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
@@ -86,16 +183,114 @@ func (a *Anthropic) Enrich(ctx context.Context, taskDesc string, beacons []confi
 
 	var anthropicResp anthropicResponse
 	if err := json.Unmarshal(body, &anthropicResp); err != nil {
+		if resp.StatusCode >= 400 {
+			return nil, newAPIError(a.name(), resp, strings.TrimSpace(string(body)))
+		}
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if anthropicResp.Error != nil {
-		return nil, fmt.Errorf("API error: %s", anthropicResp.Error.Message)
+		return nil, newAPIError(a.name(), resp, anthropicResp.Error.Message)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, newAPIError(a.name(), resp, strings.TrimSpace(string(body)))
+	}
+
+	tokens := tokenCount{
+		model:  cmp.Or(anthropicResp.Model, a.model),
+		input:  anthropicResp.Usage.InputTokens,
+		output: anthropicResp.Usage.OutputTokens,
+	}
+
+	var text strings.Builder
+	for _, block := range anthropicResp.Content {
+		if block.Type == "tool_use" && block.Name == t.name {
+			return &completion{raw: string(block.Input), callID: block.ID, tokens: tokens}, nil
+		}
+		text.WriteString(block.Text)
+	}
+
+	// No tool call: hand back the text so the retry loop can report it as malformed
+	return &completion{raw: text.String(), tokens: tokens}, nil
+}
+
+// readStream assembles a streamed reply, passing the tool call's JSON on to stream
+// as it arrives
+func (a *Anthropic) readStream(body io.Reader, t tool, stream *partialStream) (*completion, error) {
+	tokens := tokenCount{model: a.model}
+	var callID string
+	toolBlock := -1
+	var text strings.Builder
+
+	err := readEvents(body, func(data []byte) error {
+		var event anthropicEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return fmt.Errorf("failed to parse stream event: %w", err)
+		}
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				tokens.model = cmp.Or(event.Message.Model, a.model)
+				tokens.input = event.Message.Usage.InputTokens
+			}
+		case "content_block_start":
+			if b := event.ContentBlock; b != nil && b.Type == "tool_use" && b.Name == t.name && toolBlock < 0 {
+				toolBlock, callID = event.Index, b.ID
+			}
+		case "content_block_delta":
+			if event.Index == toolBlock {
+				stream.write(event.Delta.PartialJSON)
+			} else {
+				text.WriteString(event.Delta.Text)
+			}
+		case "message_delta":
+			if event.Usage != nil {
+				tokens.output = event.Usage.OutputTokens
+			}
+		case "error":
+			if event.Error != nil {
+				return newStreamError(a.name(), event.Error.Type, event.Error.Message)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	if len(anthropicResp.Content) == 0 {
-		return nil, fmt.Errorf("empty response from API")
+	if toolBlock < 0 {
+		// No tool call: hand back the text so the retry loop can report it as malformed
+		return &completion{raw: text.String(), tokens: tokens}, nil
+	}
+	// A tool call without arguments streams no deltas
+	return &completion{raw: cmp.Or(stream.String(), "{}"), callID: callID, tokens: tokens}, nil
+}
+
+// feedbackMessages replays a rejected attempt: the assistant's tool call followed
+// by an error tool_result, or plain text turns when there was no usable tool call
+func (a *Anthropic) feedbackMessages(t tool, h turn) []anthropicMessage {
+	if h.callID != "" && json.Valid([]byte(h.raw)) {
+		return []anthropicMessage{
+			{Role: "assistant", Content: []anthropicBlock{
+				{Type: "tool_use", ID: h.callID, Name: t.name, Input: json.RawMessage(h.raw)},
+			}},
+			{Role: "user", Content: []anthropicBlock{
+				{Type: "tool_result", ToolUseID: h.callID, Content: h.feedback, IsError: true},
+			}},
+		}
 	}
 
-	return parseEnrichmentResponse(anthropicResp.Content[0].Text)
+	reply := h.raw
+	if strings.TrimSpace(reply) == "" {
+		reply = "(empty reply)"
+	}
+	return []anthropicMessage{
+		{Role: "assistant", Content: reply},
+		{Role: "user", Content: h.feedback},
+	}
 }
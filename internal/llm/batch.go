@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/taskwarrior"
+)
+
+//go:embed batch.tmpl
+var batchPromptTemplate string
+
+// maxBatch is how many tasks go into one request; longer lists take several, so a
+// reply stays within the output limit
+const maxBatch = 20
+
+// batch is the reply to a batched request. The desc tags end up in the JSON schema.
+type batch struct {
+	Tasks []Enrichment `json:"tasks" desc:"One assessment per task, in the order the tasks are listed"`
+}
+
+var batchTool = tool{name: "record_enrichments", description: "Record the enrichment of every task", schema: jsonSchema(reflect.TypeOf(batch{})), maxTokens: 8192}
+
+// enrichBatch asks c for the enrichments of tasks, maxBatch at a time. The usage of
+// each request is on the first enrichment it returned, so the usages add up.
+func (r retryPolicy) enrichBatch(ctx context.Context, c completer, p promptBuilder, tasks []taskwarrior.Task, beacons []config.Beacon, projects []config.Project) ([]*Enrichment, error) {
+	var enrichments []*Enrichment
+	for chunk := range slices.Chunk(tasks, maxBatch) {
+		prompt, err := p.buildBatch(chunk, beacons, projects)
+		if err != nil {
+			return nil, err
+		}
+
+		b, spent, err := retry(ctx, r, c, prompt, batchTool, checkBatch(len(chunk)))
+		if err != nil {
+			return nil, err
+		}
+		// Attempts ran out on a reply with the wrong count
+		if err := checkBatch(len(chunk))(b); err != nil {
+			return nil, &ParseError{Provider: c.name(), Err: err}
+		}
+
+		for i := range b.Tasks {
+			enrichments = append(enrichments, &b.Tasks[i])
+		}
+		enrichments[len(enrichments)-len(chunk)].Usage = spent
+	}
+	return enrichments, nil
+}
+
+func checkBatch(n int) func(*batch) error {
+	return func(b *batch) error {
+		if len(b.Tasks) != n {
+			return fmt.Errorf("got %d assessments for %d tasks, return exactly one per task in order", len(b.Tasks), n)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,220 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bf/tg/internal/cache"
+	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/taskwarrior"
+)
+
+// Cached serves enrichments from an on-disk cache and only calls the wrapped provider
+// on a miss. Entries are keyed by the provider, the model, the description and the
+// configuration the prompt is rendered from, so any change to the fields the task
+// already has, tg learn's preferences, the beacons, projects, calendar config or
+// template invalidates them. A description that names a relative
+// date, like "call Bob tomorrow", is keyed by today's date too, so its answer isn't
+// reused once the date it resolved to is stale; the others keep until cache.ttl.
+type Cached struct {
+	provider Provider
+	store    *cache.Store
+	identity []string // provider and model, or those of every provider in a chain
+	prompt   promptBuilder
+}
+
+// cachedEnrichment is what a cache entry holds: the enrichment plus the fallback
+// chain provider that produced it, which isn't part of the schema
+type cachedEnrichment struct {
+	*Enrichment
+	Provider string `json:"provider,omitempty"`
+}
+
+func newCached(provider Provider, store *cache.Store, identity []string, prompt promptBuilder) *Cached {
+	return &Cached{provider: provider, store: store, identity: identity, prompt: prompt}
+}
+
+func (c *Cached) Enrich(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Enrichment, error) {
+	key, err := c.key(task, beacons, projects)
+	if err != nil {
+		return nil, err
+	}
+
+	// The cache is best effort: read and write failures fall through to the provider
+	if e, ok := c.get(key); ok {
+		return e, nil
+	}
+
+	enrichment, err := c.provider.Enrich(ctx, task, beacons, projects)
+	if err != nil {
+		return nil, err
+	}
+
+	// Offline heuristics are cheap and mustn't shadow a real answer once back online
+	if enrichment.Provider != rulesProviderName {
+		c.store.Put(key, cachedEnrichment{Enrichment: enrichment, Provider: enrichment.Provider})
+	}
+	return enrichment, nil
+}
+
+// EnrichBatch serves the tasks it has cached and sends the rest as one batch. A
+// task's own entry from tg add is reused, but batch results come from a different
+// prompt and are stored under the whole list, so only rerunning the same list hits them.
+func (c *Cached) EnrichBatch(ctx context.Context, tasks []taskwarrior.Task, beacons []config.Beacon, projects []config.Project) ([]*Enrichment, error) {
+	descriptions := make([]string, len(tasks))
+	for i, task := range tasks {
+		descriptions[i] = task.Description
+	}
+
+	enrichments := make([]*Enrichment, len(tasks))
+	batchKeys := make([]string, len(tasks))
+	var missing []int
+	for i, task := range tasks {
+		key, err := c.key(task, beacons, projects)
+		if err != nil {
+			return nil, err
+		}
+		batchKeys[i], err = c.batchKey(descriptions, i, beacons, projects)
+		if err != nil {
+			return nil, err
+		}
+
+		if e, ok := c.get(key); ok {
+			enrichments[i] = e
+		} else if e, ok := c.get(batchKeys[i]); ok {
+			enrichments[i] = e
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return enrichments, nil
+	}
+
+	var uncached []taskwarrior.Task
+	for _, i := range missing {
+		uncached = append(uncached, tasks[i])
+	}
+	fetched, err := c.provider.EnrichBatch(ctx, uncached, beacons, projects)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range missing {
+		e := fetched[j]
+		enrichments[i] = e
+		if e.Provider != rulesProviderName {
+			c.store.Put(batchKeys[i], cachedEnrichment{Enrichment: e, Provider: e.Provider})
+		}
+	}
+	return enrichments, nil
+}
+
+// get loads the entry under key, marked as served from the cache
+func (c *Cached) get(key string) (*Enrichment, bool) {
+	cached := cachedEnrichment{Enrichment: &Enrichment{}}
+	if ok, _ := c.store.Get(key, &cached); !ok {
+		return nil, false
+	}
+	cached.Enrichment.Cached = true
+	cached.Enrichment.Provider = cached.Provider
+	return cached.Enrichment, true
+}
+
+// Split isn't cached: it is asked for explicitly and its answer is only a proposal
+func (c *Cached) Split(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Split, error) {
+	return c.provider.Split(ctx, task, beacons, projects)
+}
+
+// cacheKey is what an entry's key is hashed from: the prompt's inputs rather than the
+// rendered prompt, whose calendar section would expire every entry at midnight. The
+// fields the task already has and tg learn's preferences change the answer, so they
+// are part of it; the few-shot examples only refine it and change whenever a task is
+// tagged, so they are left out.
+type cacheKey struct {
+	Identity    []string
+	Template    string
+	Task        string
+	Known       KnownFields `json:",omitzero"`
+	Batch       []string    `json:",omitempty"` // every description of an EnrichBatch call
+	Today       string      `json:",omitempty"` // set when Task names a date relative to today
+	Preferences []string
+	Beacons     []config.Beacon
+	Projects    []config.Project
+	UDAs        config.UDAValues
+	Calendar    config.Calendar
+}
+
+func (c *Cached) key(task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (string, error) {
+	return c.hash(cacheKey{Template: templateSource(c.prompt.templates()), Task: task.Description, Known: knownFields(task)}, beacons, projects)
+}
+
+// batchKey is where EnrichBatch stores the result for the i-th of descriptions
+func (c *Cached) batchKey(descriptions []string, i int, beacons []config.Beacon, projects []config.Project) (string, error) {
+	return c.hash(cacheKey{Template: templateSource(c.prompt.templates()), Task: descriptions[i], Batch: descriptions}, beacons, projects)
+}
+
+// hash completes k with the provider and configuration and digests it
+func (c *Cached) hash(k cacheKey, beacons []config.Beacon, projects []config.Project) (string, error) {
+	cfg := c.prompt.cfg
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	now := time.Now
+	if c.prompt.now != nil {
+		now = c.prompt.now
+	}
+	if mentionsDate(k.Task) {
+		k.Today = now().In(cfg.Calendar.Location()).Format("2006-01-02")
+	}
+	k.Identity = c.identity
+	k.Preferences = c.prompt.preferences
+	k.Beacons = beacons
+	k.Projects = projects
+	k.UDAs = cfg.UDAValues
+	k.Calendar = cfg.Calendar
+
+	data, err := json.Marshal(k)
+	if err != nil {
+		return "", fmt.Errorf("failed to build cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// mentionsDate reports whether desc names a date relative to today, like "friday",
+// "tomorrow", "in 2 weeks" or "end of the month". Absolute dates don't count.
+func mentionsDate(desc string) bool {
+	desc = strings.ToLower(desc)
+	for phrase := range dateAliases {
+		if strings.Contains(desc, phrase) {
+			return true
+		}
+	}
+
+	words := strings.FieldsFunc(desc, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+	now := time.Now()
+	for i, w := range words {
+		if _, err := time.Parse("2006-01-02", w); err == nil {
+			continue
+		}
+		candidates := []string{w}
+		if i+1 < len(words) {
+			candidates = append(candidates, w+words[i+1]) // "2 weeks"
+		}
+		for _, expr := range candidates {
+			// The week start doesn't matter for whether it is a date at all
+			if _, ok := taskwarrior.ParseDate(expr, now, time.Monday); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
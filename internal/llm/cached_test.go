@@ -0,0 +1,29 @@
+package llm
+
+import "testing"
+
+func TestMentionsDate(t *testing.T) {
+	tests := []struct {
+		desc string
+		want bool
+	}{
+		{"Call Bob tomorrow", true},
+		{"Send the report by Friday.", true},
+		{"Renew passport before end of the month", true},
+		{"Follow up in 2 weeks", true},
+		{"Book flights for march", true},
+		{"Pay rent on the 1st", true},
+		{"Plan the offsite next week", true},
+		{"Submit taxes by 2025-04-15", false},
+		{"Refactor the config loader", false},
+		{"Fix JIRA-123 login bug", false},
+		{"Review PR 42", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := mentionsDate(tt.desc); got != tt.want {
+				t.Errorf("mentionsDate(%q) = %v, want %v", tt.desc, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/taskwarrior"
+)
+
+// Chain tries its providers in order and returns the first valid enrichment, so a
+// rate-limited API or a dead network falls back to the next provider (e.g. anthropic
+// → openai → a local ollama). Each provider gets its own timeout.
+type Chain struct {
+	links     []chainLink
+	validator retryPolicy // only its check is used, to tell valid results from repairable ones
+}
+
+type chainLink struct {
+	name     string // provider/model, shown in the preview
+	provider Provider
+	timeout  time.Duration
+}
+
+func newChain(cfg *config.Config, prompt promptBuilder, meter *Meter) (*Chain, error) {
+	c := &Chain{validator: retryPolicy{cfg: cfg}}
+	for _, pc := range cfg.LLM.Providers {
+		provider, err := newBackend(cfg.ForProvider(pc), prompt, meter)
+		var keyErr *MissingKeyError
+		if errors.As(err, &keyErr) {
+			// A provider without a key can't answer; the rest of the chain still can
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fallback provider %s: %w", pc.Provider, err)
+		}
+		name := pc.Provider
+		if pc.Model != "" {
+			name += "/" + pc.Model
+		}
+		c.links = append(c.links, chainLink{name: name, provider: provider, timeout: pc.Timeout})
+	}
+	if len(c.links) == 0 {
+		rules, err := NewRules(cfg)
+		if err != nil {
+			return nil, err
+		}
+		c.links = append(c.links, chainLink{name: rulesProviderName, provider: rules})
+	}
+	return c, nil
+}
+
+func (c *Chain) Enrich(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Enrichment, error) {
+	var failed []string
+	var errs []error
+	// An enrichment Validate has to repair is kept in case no provider does better
+	var repairable *Enrichment
+
+	for i, link := range c.links {
+		linkCtx := ctx
+		if i > 0 {
+			linkCtx = withFallback(ctx, link.name)
+			reportProgress(linkCtx, Progress{Attempt: 1, MaxAttempts: 1})
+		}
+		enrichment, err := link.enrich(linkCtx, task, beacons, projects)
+		if ctx.Err() != nil {
+			// Cancelled by the caller, not a reason to try the next provider
+			return nil, ctx.Err()
+		}
+
+		if err == nil {
+			enrichment.Provider = link.name
+			if err = c.validator.check(enrichment); err == nil {
+				enrichment.Failed = failed
+				return enrichment, nil
+			}
+			if repairable == nil {
+				repairable = enrichment
+			}
+		}
+		failed = append(failed, fmt.Sprintf("%s: %v", link.name, err))
+		errs = append(errs, fmt.Errorf("%s: %w", link.name, err))
+	}
+
+	if repairable != nil {
+		repairable.Failed = failed
+		return repairable, nil
+	}
+	return nil, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
+
+// EnrichBatch tries the providers in order like Enrich and returns the first batch
+// that comes back whole; Validate repairs what needs it
+func (c *Chain) EnrichBatch(ctx context.Context, tasks []taskwarrior.Task, beacons []config.Beacon, projects []config.Project) ([]*Enrichment, error) {
+	var failed []string
+	var errs []error
+	for i, link := range c.links {
+		linkCtx := ctx
+		if i > 0 {
+			linkCtx = withFallback(ctx, link.name)
+			reportProgress(linkCtx, Progress{Attempt: 1, MaxAttempts: 1})
+		}
+		enrichments, err := link.enrichBatch(linkCtx, tasks, beacons, projects)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == nil {
+			for _, e := range enrichments {
+				e.Provider = link.name
+				e.Failed = failed
+			}
+			return enrichments, nil
+		}
+		failed = append(failed, fmt.Sprintf("%s: %v", link.name, err))
+		errs = append(errs, fmt.Errorf("%s: %w", link.name, err))
+	}
+	return nil, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
+
+// Split tries the providers in order like Enrich and returns the first split
+func (c *Chain) Split(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Split, error) {
+	var errs []error
+	for i, link := range c.links {
+		linkCtx := ctx
+		if i > 0 {
+			linkCtx = withFallback(ctx, link.name)
+			reportProgress(linkCtx, Progress{Attempt: 1, MaxAttempts: 1})
+		}
+		s, err := link.split(linkCtx, task, beacons, projects)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == nil {
+			s.Provider = link.name
+			return s, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", link.name, err))
+	}
+	return nil, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
+
+func (l chainLink) enrich(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Enrichment, error) {
+	if l.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.timeout)
+		defer cancel()
+	}
+	enrichment, err := l.provider.Enrich(ctx, task, beacons, projects)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("no answer within %s", l.timeout)
+	}
+	return enrichment, err
+}
+
+// enrichBatch allows the link's timeout per task, as the reply grows with the list
+func (l chainLink) enrichBatch(ctx context.Context, tasks []taskwarrior.Task, beacons []config.Beacon, projects []config.Project) ([]*Enrichment, error) {
+	timeout := l.timeout * time.Duration(len(tasks))
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	enrichments, err := l.provider.EnrichBatch(ctx, tasks, beacons, projects)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("no answer within %s", timeout)
+	}
+	return enrichments, err
+}
+
+func (l chainLink) split(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Split, error) {
+	if l.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.timeout)
+		defer cancel()
+	}
+	s, err := l.provider.Split(ctx, task, beacons, projects)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("no answer within %s", l.timeout)
+	}
+	return s, err
+}
+
+// withFallback tags the progress reports made under ctx with the provider being fallen back to
+func withFallback(ctx context.Context, provider string) context.Context {
+	fn, ok := ctx.Value(progressKey{}).(func(Progress))
+	if !ok {
+		return ctx
+	}
+	return WithProgress(ctx, func(p Progress) {
+		p.Fallback = provider
+		fn(p)
+	})
+}
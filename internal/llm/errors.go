@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseError is returned when a provider's reply can't be decoded into an Enrichment.
+// Raw keeps the undecoded reply for debugging; it is not part of the error message.
+type ParseError struct {
+	Provider string
+	Raw      string
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s returned a malformed enrichment: %v", e.Provider, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists the suggested values Validate could not repair
+type ValidationError struct {
+	Fixes []Fix
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fixes))
+	for i, f := range e.Fixes {
+		parts[i] = f.String()
+	}
+	return "invalid enrichment: " + strings.Join(parts, "; ")
+}
+
+// MissingKeyError is returned when a provider needs an API key that isn't set
+type MissingKeyError struct {
+	Provider string
+	Env      string // the configured environment variable
+}
+
+func (e *MissingKeyError) Error() string {
+	return fmt.Sprintf("%s_API_KEY not set (configured env: %s)", strings.ToUpper(e.Provider), e.Env)
+}
+
+// APIError is a non-2xx response from a provider's API
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // from the Retry-After header, 0 when absent
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error (%d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// Temporary reports whether the request is worth retrying (rate limited or server side)
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// newAPIError builds an APIError from a failed response; message is the API's
+// own error text when the body could be parsed, the raw body otherwise
+func newAPIError(provider string, resp *http.Response, message string) *APIError {
+	err := &APIError{Provider: provider, StatusCode: resp.StatusCode, Message: message}
+	if secs, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil {
+		err.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return err
+}
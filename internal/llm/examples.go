@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/similar"
+	"github.com/bf/tg/internal/taskwarrior"
+)
+
+// Example is one of the user's own tagged tasks, shown to the model as a worked example
+type Example struct {
+	Description string
+	Beacons     []string
+	Directions  []string
+	Project     string
+	Priority    string
+	Effort      string
+	Impact      string
+	Estimate    string
+	Fun         string
+	Blocks      int
+}
+
+// minExampleScore keeps tasks that only share a common word out of the examples
+const minExampleScore = 0.15
+
+// projectBoost is added to the score of tasks in the project the description's
+// keywords point at, so tasks of the same project qualify even without shared words
+const projectBoost = 0.25
+
+// exampleSource picks the past tasks most similar to the one being enriched. Tasks are
+// exported once, on first use; only tasks carrying a beacon tag count, since those
+// are the ones the user tagged (or accepted tags for).
+type exampleSource struct {
+	count   int
+	beacons []config.Beacon
+	load    func() ([]taskwarrior.Task, error)
+
+	once  sync.Once
+	tasks []taskwarrior.Task
+	index *similar.Index
+}
+
+func newExampleSource(cfg *config.Config) *exampleSource {
+	return &exampleSource{
+		count:   cfg.LLM.FewShot,
+		beacons: cfg.Beacons,
+		load: func() ([]taskwarrior.Task, error) {
+			return taskwarrior.New().Export("status:pending or status:completed")
+		},
+	}
+}
+
+func (s *exampleSource) init() {
+	beaconTags, _, _ := tagSets(s.beacons)
+
+	// Examples are best effort: without task data the prompt simply has none
+	tasks, _ := s.load()
+	var descriptions []string
+	for _, t := range tasks {
+		if slices.ContainsFunc(t.Tags, func(tag string) bool { return slices.Contains(beaconTags, tag) }) {
+			s.tasks = append(s.tasks, t)
+			descriptions = append(descriptions, t.Description)
+		}
+	}
+	s.index = similar.NewIndex(descriptions)
+}
+
+// examples returns up to count tasks resembling taskDesc, most similar first
+func (s *exampleSource) examples(taskDesc string, projects []config.Project) []Example {
+	if s == nil || s.count <= 0 {
+		return nil
+	}
+	s.once.Do(s.init)
+
+	project, _ := detectProject(taskDesc, projects)
+	beaconTags, directionTags, _ := tagSets(s.beacons)
+
+	type candidate struct {
+		task  taskwarrior.Task
+		score float64
+	}
+	lexical := make(map[int]float64)
+	for _, m := range s.index.Search(taskDesc, 0) {
+		lexical[m.Index] = m.Score
+	}
+
+	var candidates []candidate
+	for i, t := range s.tasks {
+		// The task being re-enriched shouldn't serve as its own example
+		if strings.EqualFold(t.Description, taskDesc) {
+			continue
+		}
+		score := lexical[i]
+		if project != "" && (t.Project == project || strings.HasPrefix(t.Project, project+".")) {
+			score += projectBoost
+		}
+		if score >= minExampleScore {
+			candidates = append(candidates, candidate{t, score})
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return cmp.Compare(b.score, a.score)
+	})
+
+	var examples []Example
+	for _, c := range candidates[:min(s.count, len(candidates))] {
+		e := Example{
+			Description: c.task.Description,
+			Project:     c.task.Project,
+			Priority:    c.task.Priority,
+			Effort:      c.task.Effort,
+			Impact:      c.task.Impact,
+			Estimate:    c.task.Estimate,
+			Fun:         c.task.Fun,
+			Blocks:      c.task.Blocks,
+		}
+		for _, tag := range c.task.Tags {
+			switch {
+			case slices.Contains(beaconTags, tag):
+				e.Beacons = append(e.Beacons, tag)
+			case slices.Contains(directionTags, tag):
+				e.Directions = append(e.Directions, tag)
+			}
+		}
+		examples = append(examples, e)
+	}
+	return examples
+}
+
+// detectProject returns the first project with a keyword in the description, and the keyword
+func detectProject(taskDesc string, projects []config.Project) (string, string) {
+	desc := strings.ToLower(taskDesc)
+	for _, p := range projects {
+		for _, kw := range p.Keywords {
+			if kw != "" && strings.Contains(desc, strings.ToLower(kw)) {
+				return p.Name, kw
+			}
+		}
+	}
+	return "", ""
+}
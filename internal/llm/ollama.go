@@ -2,19 +2,25 @@ package llm
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/taskwarrior"
 )
 
 type Ollama struct {
 	baseURL string
 	model   string
 	client  *http.Client
+	retry   retryPolicy
+	prompt  promptBuilder
 }
 
 func NewOllama(baseURL, model string) *Ollama {
@@ -25,29 +31,73 @@ func NewOllama(baseURL, model string) *Ollama {
 		baseURL: baseURL,
 		model:   model,
 		client:  &http.Client{},
+		retry:   retryPolicy{maxAttempts: defaultMaxAttempts},
 	}
 }
 
 type ollamaRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
-	Format string `json:"format"`
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   any             `json:"format"` // "json" or a JSON schema
 }
 
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ollamaResponse is a whole reply, or one line of a streamed one
 type ollamaResponse struct {
-	Response string `json:"response"`
-	Error    string `json:"error,omitempty"`
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Done            bool          `json:"done"`
+	Error           string        `json:"error,omitempty"`
+}
+
+func (o *Ollama) Enrich(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Enrichment, error) {
+	prompt, err := o.prompt.build(task, beacons, projects)
+	if err != nil {
+		return nil, err
+	}
+	return o.retry.enrich(ctx, o, prompt)
+}
+
+func (o *Ollama) EnrichBatch(ctx context.Context, tasks []taskwarrior.Task, beacons []config.Beacon, projects []config.Project) ([]*Enrichment, error) {
+	return o.retry.enrichBatch(ctx, o, o.prompt, tasks, beacons, projects)
+}
+
+func (o *Ollama) Split(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Split, error) {
+	prompt, err := o.prompt.buildSplit(task, beacons, projects)
+	if err != nil {
+		return nil, err
+	}
+	return o.retry.split(ctx, o, prompt)
+}
+
+func (o *Ollama) name() string {
+	return "ollama"
 }
 
-func (o *Ollama) Enrich(ctx context.Context, taskDesc string, beacons []config.Beacon, projects []config.Project) (*Enrichment, error) {
-	prompt := buildPrompt(taskDesc, beacons, projects)
+func (o *Ollama) complete(ctx context.Context, prompt string, t tool, history []turn) (*completion, error) {
+	stream := newPartialStream(ctx)
+	messages := []ollamaMessage{
+		{Role: "user", Content: prompt},
+	}
+	for _, h := range history {
+		messages = append(messages,
+			ollamaMessage{Role: "assistant", Content: h.raw},
+			ollamaMessage{Role: "user", Content: h.feedback},
+		)
+	}
 
 	reqBody := ollamaRequest{
-		Model:  o.model,
-		Prompt: prompt,
-		Stream: false,
-		Format: "json",
+		Model:    o.model,
+		Messages: messages,
+		Stream:   stream != nil,
+		Format:   t.schema,
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -55,7 +105,7 @@ func (o *Ollama) Enrich(ctx context.Context, taskDesc string, beacons []config.B
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/api/generate", bytes.NewReader(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/api/chat", bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -68,6 +118,10 @@ func (o *Ollama) Enrich(ctx context.Context, taskDesc string, beacons []config.B
 	}
 	defer resp.Body.Close()
 
+	if stream != nil && resp.StatusCode < 400 {
+		return o.readStream(resp.Body, stream)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
@@ -75,12 +129,52 @@ func (o *Ollama) Enrich(ctx context.Context, taskDesc string, beacons []config.B
 
 	var ollamaResp ollamaResponse
 	if err := json.Unmarshal(body, &ollamaResp); err != nil {
+		if resp.StatusCode >= 400 {
+			return nil, newAPIError(o.name(), resp, strings.TrimSpace(string(body)))
+		}
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if ollamaResp.Error != "" {
-		return nil, fmt.Errorf("API error: %s", ollamaResp.Error)
+		return nil, newAPIError(o.name(), resp, ollamaResp.Error)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, newAPIError(o.name(), resp, strings.TrimSpace(string(body)))
+	}
+
+	return &completion{
+		raw:    ollamaResp.Message.Content,
+		tokens: tokenCount{model: cmp.Or(ollamaResp.Model, o.model), input: ollamaResp.PromptEvalCount, output: ollamaResp.EvalCount},
+	}, nil
+}
+
+// readStream assembles a streamed reply, passing the content on to stream as it
+// arrives. The last line carries the token counts.
+func (o *Ollama) readStream(body io.Reader, stream *partialStream) (*completion, error) {
+	tokens := tokenCount{model: o.model}
+
+	err := readLines(body, func(line []byte) error {
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return newStreamError(o.name(), "", chunk.Error)
+		}
+		stream.write(chunk.Message.Content)
+		if chunk.Done {
+			tokens = tokenCount{model: cmp.Or(chunk.Model, o.model), input: chunk.PromptEvalCount, output: chunk.EvalCount}
+		}
+		return nil
+	})
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	return parseEnrichmentResponse(ollamaResp.Response)
+	return &completion{raw: stream.String(), tokens: tokens}, nil
 }
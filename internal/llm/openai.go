@@ -2,19 +2,38 @@ package llm
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/taskwarrior"
 )
 
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// defaultAzureAPIVersion is the Azure OpenAI API version used for deployments when
+// llm.api_version isn't set; it is the first GA version with structured outputs
+const defaultAzureAPIVersion = "2024-10-21"
+
+// OpenAI talks to the OpenAI chat completions API or anything compatible with it,
+// such as LiteLLM, vLLM or Azure OpenAI gateways
 type OpenAI struct {
-	apiKey string
-	model  string
-	client *http.Client
+	apiKey     string
+	model      string
+	baseURL    string
+	apiVersion string
+	deployment string
+	headers    map[string]string
+	client     *http.Client
+	retry      retryPolicy
+	prompt     promptBuilder
 }
 
 func NewOpenAI(apiKey, model string) *OpenAI {
@@ -22,15 +41,70 @@ func NewOpenAI(apiKey, model string) *OpenAI {
 		model = "gpt-4o"
 	}
 	return &OpenAI{
-		apiKey: apiKey,
-		model:  model,
-		client: &http.Client{},
+		apiKey:  apiKey,
+		model:   model,
+		baseURL: defaultOpenAIBaseURL,
+		client:  &http.Client{},
+		retry:   retryPolicy{maxAttempts: defaultMaxAttempts},
+	}
+}
+
+// endpoint returns the chat completions URL. With a deployment it follows Azure's
+// layout, <base>/openai/deployments/<deployment>/chat/completions?api-version=...;
+// otherwise the base URL is expected to include the version, like .../v1.
+func (o *OpenAI) endpoint() (string, error) {
+	base := strings.TrimRight(o.baseURL, "/")
+	if base == "" {
+		base = defaultOpenAIBaseURL
+	}
+
+	path := "/chat/completions"
+	apiVersion := o.apiVersion
+	if o.deployment != "" {
+		// Deployments only exist on an Azure resource, never on api.openai.com
+		if base == defaultOpenAIBaseURL {
+			return "", fmt.Errorf("deployment %q needs the base_url of an Azure OpenAI resource", o.deployment)
+		}
+		base = strings.TrimSuffix(base, "/openai")
+		path = "/openai/deployments/" + url.PathEscape(o.deployment) + path
+		if apiVersion == "" {
+			apiVersion = defaultAzureAPIVersion
+		}
 	}
+
+	u, err := url.Parse(base + path)
+	if err != nil {
+		return "", fmt.Errorf("invalid base_url %q: %w", o.baseURL, err)
+	}
+	if apiVersion != "" {
+		q := u.Query()
+		q.Set("api-version", apiVersion)
+		u.RawQuery = q.Encode()
+	}
+	return u.String(), nil
 }
 
 type openaiRequest struct {
-	Model    string           `json:"model"`
-	Messages []openaiMessage  `json:"messages"`
+	Model          string                `json:"model"`
+	Messages       []openaiMessage       `json:"messages"`
+	ResponseFormat *openaiResponseFormat `json:"response_format,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *openaiStreamOptions  `json:"stream_options,omitempty"`
+}
+
+type openaiStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openaiResponseFormat struct {
+	Type       string           `json:"type"`
+	JSONSchema openaiJSONSchema `json:"json_schema"`
+}
+
+type openaiJSONSchema struct {
+	Name   string         `json:"name"`
+	Strict bool           `json:"strict"`
+	Schema map[string]any `json:"schema"`
 }
 
 type openaiMessage struct {
@@ -38,40 +112,109 @@ type openaiMessage struct {
 	Content string `json:"content"`
 }
 
+// openaiResponse is a whole reply, or one chunk of a streamed one
 type openaiResponse struct {
+	Model   string `json:"model"`
 	Choices []struct {
 		Message struct {
 			Content string `json:"content"`
+			Refusal string `json:"refusal"`
 		} `json:"message"`
+		Delta struct {
+			Content string `json:"content"`
+			Refusal string `json:"refusal"`
+		} `json:"delta"`
 	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
 	Error *struct {
+		Type    string `json:"type"`
 		Message string `json:"message"`
 	} `json:"error"`
 }
 
-func (o *OpenAI) Enrich(ctx context.Context, taskDesc string, beacons []config.Beacon, projects []config.Project) (*Enrichment, error) {
-	prompt := buildPrompt(taskDesc, beacons, projects)
+func (o *OpenAI) Enrich(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Enrichment, error) {
+	prompt, err := o.prompt.build(task, beacons, projects)
+	if err != nil {
+		return nil, err
+	}
+	return o.retry.enrich(ctx, o, prompt)
+}
+
+func (o *OpenAI) EnrichBatch(ctx context.Context, tasks []taskwarrior.Task, beacons []config.Beacon, projects []config.Project) ([]*Enrichment, error) {
+	return o.retry.enrichBatch(ctx, o, o.prompt, tasks, beacons, projects)
+}
+
+func (o *OpenAI) Split(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Split, error) {
+	prompt, err := o.prompt.buildSplit(task, beacons, projects)
+	if err != nil {
+		return nil, err
+	}
+	return o.retry.split(ctx, o, prompt)
+}
+
+func (o *OpenAI) name() string {
+	return "openai"
+}
+
+func (o *OpenAI) complete(ctx context.Context, prompt string, t tool, history []turn) (*completion, error) {
+	stream := newPartialStream(ctx)
+	messages := []openaiMessage{
+		{Role: "system", Content: "You are a task enrichment assistant. Respond only with valid JSON."},
+		{Role: "user", Content: prompt},
+	}
+	for _, h := range history {
+		messages = append(messages,
+			openaiMessage{Role: "assistant", Content: h.raw},
+			openaiMessage{Role: "user", Content: h.feedback},
+		)
+	}
 
 	reqBody := openaiRequest{
-		Model: o.model,
-		Messages: []openaiMessage{
-			{Role: "system", Content: "You are a task enrichment assistant. Respond only with valid JSON."},
-			{Role: "user", Content: prompt},
+		Model:    o.model,
+		Messages: messages,
+		ResponseFormat: &openaiResponseFormat{
+			Type: "json_schema",
+			JSONSchema: openaiJSONSchema{
+				Name:   t.name,
+				Strict: true,
+				Schema: t.schema,
+			},
 		},
 	}
+	if stream != nil {
+		reqBody.Stream = true
+		reqBody.StreamOptions = &openaiStreamOptions{IncludeUsage: true}
+	}
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(jsonBody))
+	endpoint, err := o.endpoint()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	switch {
+	case o.apiKey == "":
+		// Local gateways such as vLLM often run without authentication
+	case o.deployment != "":
+		req.Header.Set("api-key", o.apiKey)
+	default:
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	for name, value := range o.headers {
+		req.Header.Set(name, value)
+	}
 
 	resp, err := o.client.Do(req)
 	if err != nil {
@@ -79,6 +222,10 @@ func (o *OpenAI) Enrich(ctx context.Context, taskDesc string, beacons []config.B
 	}
 	defer resp.Body.Close()
 
+	if stream != nil && resp.StatusCode < 400 {
+		return o.readStream(resp.Body, stream)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
@@ -86,16 +233,75 @@ func (o *OpenAI) Enrich(ctx context.Context, taskDesc string, beacons []config.B
 
 	var openaiResp openaiResponse
 	if err := json.Unmarshal(body, &openaiResp); err != nil {
+		if resp.StatusCode >= 400 {
+			return nil, newAPIError(o.name(), resp, strings.TrimSpace(string(body)))
+		}
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if openaiResp.Error != nil {
-		return nil, fmt.Errorf("API error: %s", openaiResp.Error.Message)
+		return nil, newAPIError(o.name(), resp, openaiResp.Error.Message)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, newAPIError(o.name(), resp, strings.TrimSpace(string(body)))
 	}
 
 	if len(openaiResp.Choices) == 0 {
 		return nil, fmt.Errorf("empty response from API")
 	}
 
-	return parseEnrichmentResponse(openaiResp.Choices[0].Message.Content)
+	// Azure names the deployment in the URL; the model it runs comes back in the reply
+	tokens := tokenCount{
+		model:  cmp.Or(openaiResp.Model, o.deployment, o.model),
+		input:  openaiResp.Usage.PromptTokens,
+		output: openaiResp.Usage.CompletionTokens,
+	}
+
+	message := openaiResp.Choices[0].Message
+	if message.Refusal != "" {
+		return nil, &ParseError{Provider: o.name(), Raw: message.Refusal, Err: fmt.Errorf("model refused: %s", message.Refusal)}
+	}
+
+	return &completion{raw: message.Content, tokens: tokens}, nil
+}
+
+// readStream assembles a streamed reply, passing the content on to stream as it
+// arrives. Usage comes in a last chunk without choices.
+func (o *OpenAI) readStream(body io.Reader, stream *partialStream) (*completion, error) {
+	tokens := tokenCount{model: cmp.Or(o.deployment, o.model)}
+	var refusal strings.Builder
+
+	err := readEvents(body, func(data []byte) error {
+		var chunk openaiResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return newStreamError(o.name(), chunk.Error.Type, chunk.Error.Message)
+		}
+		if chunk.Model != "" {
+			tokens.model = chunk.Model
+		}
+		if chunk.Usage.PromptTokens > 0 || chunk.Usage.CompletionTokens > 0 {
+			tokens.input, tokens.output = chunk.Usage.PromptTokens, chunk.Usage.CompletionTokens
+		}
+		for _, choice := range chunk.Choices {
+			stream.write(choice.Delta.Content)
+			refusal.WriteString(choice.Delta.Refusal)
+		}
+		return nil
+	})
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if refusal.Len() > 0 {
+		return nil, &ParseError{Provider: o.name(), Raw: refusal.String(), Err: fmt.Errorf("model refused: %s", refusal.String())}
+	}
+	return &completion{raw: stream.String(), tokens: tokens}, nil
 }
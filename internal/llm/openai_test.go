@@ -0,0 +1,157 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bf/tg/internal/config"
+)
+
+// capturedRequest is what the test server saw of the last request
+type capturedRequest struct {
+	path   string
+	query  string
+	header http.Header
+}
+
+func newOpenAIServer(t *testing.T) (*httptest.Server, *capturedRequest) {
+	t.Helper()
+	got := &capturedRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.path = r.URL.Path
+		got.query = r.URL.RawQuery
+		got.header = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"gpt-4o","choices":[{"message":{"content":"{}"}}],"usage":{"prompt_tokens":1,"completion_tokens":1}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+func TestOpenAIRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiKey     string
+		deployment string
+		apiVersion string
+		suffix     string // appended to the server URL for base_url
+		headers    map[string]string
+
+		wantPath    string
+		wantQuery   string
+		wantAPIKey  string
+		wantBearer  string
+		wantHeaders map[string]string
+	}{
+		{
+			name:       "plain",
+			apiKey:     "sk-test",
+			suffix:     "/v1",
+			wantPath:   "/v1/chat/completions",
+			wantBearer: "Bearer sk-test",
+		},
+		{
+			name:       "api version without deployment",
+			apiKey:     "sk-test",
+			apiVersion: "2024-06-01",
+			suffix:     "/v1",
+			wantPath:   "/v1/chat/completions",
+			wantQuery:  "api-version=2024-06-01",
+			wantBearer: "Bearer sk-test",
+		},
+		{
+			name:       "azure deployment",
+			apiKey:     "azure-key",
+			deployment: "gpt-4o-prod",
+			wantPath:   "/openai/deployments/gpt-4o-prod/chat/completions",
+			wantQuery:  "api-version=" + defaultAzureAPIVersion,
+			wantAPIKey: "azure-key",
+		},
+		{
+			name:       "azure deployment with base ending in /openai",
+			apiKey:     "azure-key",
+			deployment: "gpt-4o-prod",
+			apiVersion: "2025-01-01-preview",
+			suffix:     "/openai/",
+			wantPath:   "/openai/deployments/gpt-4o-prod/chat/completions",
+			wantQuery:  "api-version=2025-01-01-preview",
+			wantAPIKey: "azure-key",
+		},
+		{
+			name:     "no key",
+			suffix:   "/v1",
+			wantPath: "/v1/chat/completions",
+		},
+		{
+			name:   "custom headers",
+			apiKey: "sk-test",
+			suffix: "/v1",
+			headers: map[string]string{
+				"OpenAI-Organization": "org-123",
+				"X-Gateway-Route":     "tg",
+			},
+			wantPath:   "/v1/chat/completions",
+			wantBearer: "Bearer sk-test",
+			wantHeaders: map[string]string{
+				"OpenAI-Organization": "org-123",
+				"X-Gateway-Route":     "tg",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, got := newOpenAIServer(t)
+
+			o := NewOpenAI(tt.apiKey, "gpt-4o")
+			o.baseURL = srv.URL + tt.suffix
+			o.deployment = tt.deployment
+			o.apiVersion = tt.apiVersion
+			o.headers = tt.headers
+
+			if _, err := o.complete(context.Background(), "prompt", enrichmentTool, nil); err != nil {
+				t.Fatalf("complete: %v", err)
+			}
+
+			if got.path != tt.wantPath {
+				t.Errorf("path = %q, want %q", got.path, tt.wantPath)
+			}
+			if got.query != tt.wantQuery {
+				t.Errorf("query = %q, want %q", got.query, tt.wantQuery)
+			}
+			if v := got.header.Get("api-key"); v != tt.wantAPIKey {
+				t.Errorf("api-key = %q, want %q", v, tt.wantAPIKey)
+			}
+			if v := got.header.Get("Authorization"); v != tt.wantBearer {
+				t.Errorf("Authorization = %q, want %q", v, tt.wantBearer)
+			}
+			for name, want := range tt.wantHeaders {
+				if v := got.header.Get(name); v != want {
+					t.Errorf("%s = %q, want %q", name, v, want)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAIDeploymentNeedsBaseURL(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.LLM.Provider = "openai"
+	cfg.LLM.APIKeyEnv = "TG_TEST_AZURE_KEY"
+	t.Setenv("TG_TEST_AZURE_KEY", "azure-key")
+	cfg.LLM.Deployment = "gpt-4o-prod"
+
+	_, err := newBackend(cfg, promptBuilder{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "base_url") {
+		t.Fatalf("newBackend err = %v, want one asking for base_url", err)
+	}
+
+	o := NewOpenAI("azure-key", "gpt-4o")
+	o.deployment = "gpt-4o-prod"
+	if _, err := o.endpoint(); err == nil {
+		t.Fatal("endpoint with a deployment on api.openai.com should fail")
+	}
+}
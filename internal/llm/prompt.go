@@ -1,138 +1,320 @@
 package llm
 
 import (
+	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/feedback"
+	"github.com/bf/tg/internal/taskwarrior"
 )
 
-func buildPrompt(taskDesc string, beacons []config.Beacon, projects []config.Project) string {
-	var sb strings.Builder
+//go:embed prompt.tmpl
+var defaultPromptTemplate string
+
+// sectionsTemplate defines the parts the enrichment, batch and split prompts share
+//
+//go:embed sections.tmpl
+var sectionsTemplate string
+
+// defaultPrompt is the built-in template set: the enrichment prompt, with the batch
+// and split prompts and the shared sections defined alongside it
+var defaultPrompt = template.Must(parsePrompt("prompt", ""))
+
+// PromptData is what prompt templates are rendered with
+type PromptData struct {
+	Task        string      // the description
+	Tasks       []string    // the descriptions, numbered from 1, when several are enriched at once
+	Known       KnownFields // what the task already has set, empty for a new task
+	Beacons     []config.Beacon
+	Projects    []config.Project
+	UDAs        config.UDAValues // allowed values of priority, effort, impact, est and fun
+	Calendar    PromptCalendar
+	Examples    []Example // the user's own tasks most similar to this one
+	Preferences []string  // corrections the user keeps making, from tg learn
+	Schema      string    // name of the structured-output schema the reply must use
+}
+
+// KnownFields is what an existing task already has set. The model is told to keep
+// these values and fill in the rest.
+type KnownFields struct {
+	Project     string
+	Priority    string
+	Due         string
+	Scheduled   string
+	Tags        []string
+	Effort      string
+	Impact      string
+	Estimate    string
+	Fun         string
+	Blocks      int
+	Annotations []string
+	UDAs        []KnownUDA // sorted by name
+}
+
+// KnownUDA is a UDA outside tg's own, such as bugwarrior's jiraurl
+type KnownUDA struct {
+	Name  string
+	Value string
+}
+
+// Empty reports whether nothing but the description is known
+func (k KnownFields) Empty() bool {
+	return k.Project == "" && k.Priority == "" && k.Due == "" && k.Scheduled == "" &&
+		len(k.Tags) == 0 && k.Effort == "" && k.Impact == "" && k.Estimate == "" &&
+		k.Fun == "" && k.Blocks == 0 && len(k.Annotations) == 0 && len(k.UDAs) == 0
+}
+
+// maxKnownValue caps annotations and UDAs such as githubbody, which can run to pages
+const maxKnownValue = 400
+
+// maxKnownAnnotations is how many of the latest annotations go into the prompt
+const maxKnownAnnotations = 10
+
+func knownFields(task taskwarrior.Task) KnownFields {
+	k := KnownFields{
+		Project:   task.Project,
+		Priority:  task.Priority,
+		Due:       taskwarrior.FormatDate(task.Due),
+		Scheduled: taskwarrior.FormatDate(task.Scheduled),
+		Tags:      task.Tags,
+		Effort:    task.Effort,
+		Impact:    task.Impact,
+		Estimate:  task.Estimate,
+		Fun:       task.Fun,
+		Blocks:    task.Blocks,
+	}
+	annotations := task.Annotations[max(len(task.Annotations)-maxKnownAnnotations, 0):]
+	for _, a := range annotations {
+		k.Annotations = append(k.Annotations, clip(a.Description))
+	}
+	for name, value := range task.UDAs {
+		k.UDAs = append(k.UDAs, KnownUDA{Name: name, Value: clip(value)})
+	}
+	slices.SortFunc(k.UDAs, func(a, b KnownUDA) int { return strings.Compare(a.Name, b.Name) })
+	return k
+}
 
-	sb.WriteString(`You are a task enrichment assistant. Analyze the given task and suggest appropriate tags and metadata based on the user's personal goal system called "Beacons".
+// clip puts a value on one line and shortens it to maxKnownValue runes
+func clip(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > maxKnownValue {
+		s = string(r[:maxKnownValue]) + "…"
+	}
+	return s
+}
+
+// PromptCalendar tells the model what today is and which days are worked
+type PromptCalendar struct {
+	Today       time.Time
+	Zone        string
+	WorkingDays []string
+	Upcoming    []CalendarDay    // the next two weeks, so the model needn't do weekday arithmetic
+	Holidays    []config.Holiday // within the next year
+}
 
-## Beacons System
-The user organizes tasks around high-level life goals (Beacons) and specific paths to achieve them (Directions).
-Tasks that align with MULTIPLE beacons should be prioritized higher.
-Tasks that don't align with ANY beacon should be marked as "waste".
+// CalendarDay is one of the upcoming days listed in the prompt
+type CalendarDay struct {
+	Date    time.Time
+	Working bool
+}
+
+// calendarHorizon is how many upcoming days are spelled out in the prompt
+const calendarHorizon = 14
+
+// promptBuilder renders the enrichment prompt from a template and the context it
+// needs beyond the task itself
+type promptBuilder struct {
+	tmpl        *template.Template // nil means the embedded default
+	cfg         *config.Config     // nil means no calendar or UDA configuration
+	now         func() time.Time   // nil means time.Now
+	examples    *exampleSource     // nil means no few-shot examples
+	preferences []string
+}
 
-### Available Beacons and their Directions:
-`)
+// newPromptBuilder uses the template at llm.prompt_template, or the embedded default
+func newPromptBuilder(cfg *config.Config) (promptBuilder, error) {
+	p := promptBuilder{tmpl: defaultPrompt, cfg: cfg, now: time.Now, examples: newExampleSource(cfg)}
 
-	for _, beacon := range beacons {
-		sb.WriteString(fmt.Sprintf("\n**%s** (`%s`): %s\n", beacon.Name, beacon.Tag, beacon.Description))
-		sb.WriteString("Directions:\n")
-		for _, dir := range beacon.Directions {
-			sb.WriteString(fmt.Sprintf("  - %s (`%s`): %s\n", dir.Name, dir.Tag, dir.Description))
+	// Preferences are best effort: without tg learn's output the prompt simply has none
+	if path, err := feedback.DefaultPreferencesPath(); err == nil {
+		p.preferences, _ = feedback.LoadPreferences(path)
+	}
+
+	if cfg.LLM.PromptTemplate == "" {
+		return p, nil
+	}
+
+	path := cfg.LLM.PromptTemplate
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
 		}
 	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return p, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	tmpl, err := parsePrompt(filepath.Base(path), string(text))
+	if err != nil {
+		return p, fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+	p.tmpl = tmpl
+	return p, nil
+}
 
-	if len(projects) > 0 {
-		sb.WriteString("\n### Available Projects:\n")
-		for _, proj := range projects {
-			sb.WriteString(fmt.Sprintf("- %s (keywords: %s)\n", proj.Name, strings.Join(proj.Keywords, ", ")))
+// parsePrompt parses the built-in templates and then custom, whose body replaces the
+// enrichment prompt and whose {{define}}s replace the built-in sections and the batch
+// and split prompts of the same name. A custom template that only redefines sections
+// keeps the built-in enrichment prompt.
+func parsePrompt(name, custom string) (*template.Template, error) {
+	tmpl := template.New(name).Funcs(template.FuncMap{
+		"join": strings.Join,
+		"add":  func(a, b int) int { return a + b },
+	})
+	for _, text := range []string{sectionsTemplate, defaultPromptTemplate, batchPromptTemplate, splitPromptTemplate, custom} {
+		if _, err := tmpl.Parse(text); err != nil {
+			return nil, err
 		}
 	}
+	return tmpl, nil
+}
+
+// templates returns the template set prompts are rendered from
+func (p promptBuilder) templates() *template.Template {
+	if p.tmpl == nil {
+		return defaultPrompt
+	}
+	return p.tmpl
+}
 
-	sb.WriteString(fmt.Sprintf(`
-## Task Assessment Dimensions
-
-### Effort (mental/cognitive difficulty)
-- E (Easy): Quick, straightforward, low cognitive load
-- N (Normal): Standard complexity, moderate thinking required
-- D (Difficult): Complex, requires deep focus, mentally taxing
-
-### Impact (value delivered)
-- H (High): Benefits many people, unlocks future progress, significant consequences if skipped
-- M (Medium): Moderate value, helps some people or processes
-- L (Low): Limited impact, nice-to-have
-
-### Time Estimate (use pessimistic estimation)
-Values: 15m, 30m, 1h, 2h, 4h, 8h, 2d
-Ask: "Would X time be enough?" - when answer is "maybe", double it.
-
-### Fun (enjoyment level)
-- H (High): Enjoyable, engaging task
-- M (Medium): Neutral
-- L (Low): Boring, tedious (these get urgency bump to get them done)
-
-### Blocking (how many things/people this unblocks)
-- 0: Doesn't block anything
-- 1-2: Blocks a few things (e.g., a feature that enables 1-2 other tasks)
-- 3-5: Significant blocker (e.g., API that multiple features depend on, review blocking teammates)
-- 6+: Critical blocker (e.g., infrastructure change blocking entire team, deployment blocker)
-
-Examples:
-- "Deploy API to production" might block=5 (multiple teams waiting)
-- "Fix typo in docs" block=0 (nobody waiting)
-- "Review PR for authentication" block=2 (author + downstream feature)
-- "Set up CI pipeline" block=8 (blocks entire team from deploying)
-
-### Due Dates
-- **due**: Hard deadline - must be done by this date (external pressure, meetings, launches)
-- **scheduled**: Soft due date - when you'd PREFER to do this task (internal preference)
-
-Use scheduled for tasks without external deadlines but with desired timing.
-Only set due when there's actual external pressure/deadline.
-
-## Task to Analyze
-"%s"
-
-## Instructions
-1. Analyze the task description
-2. Identify which Beacons this task contributes to (can be multiple)
-3. Identify specific Directions within those Beacons
-4. Suggest a project if keywords match
-5. Suggest priority (H=high, M=medium, L=low) based on external pressure/deadlines
-6. Assess effort, impact, time estimate, fun level, and blocking count
-7. Suggest due date only if there's a clear HARD deadline in the task
-8. Suggest scheduled date for when you'd prefer to do the task (soft due date)
-9. Estimate how many things/people this task unblocks (blocks field)
-10. Optionally improve the description to be more actionable
-11. If the task doesn't align with any beacon, mark it as waste
-
-Respond with ONLY a JSON object in this exact format:
-{
-  "description": "improved task description or original if no improvement needed",
-  "beacons": ["b.beacon1", "b.beacon2"],
-  "directions": ["d.direction1", "d.direction2"],
-  "project": "project-name or empty string",
-  "priority": "H/M/L or empty string",
-  "due": "hard deadline in taskwarrior format (e.g., '2024-12-01', 'friday') or empty string",
-  "scheduled": "soft due date - when you'd prefer to work on it (e.g., 'monday', '2024-11-25') or empty string",
-  "effort": "E/N/D",
-  "impact": "H/M/L",
-  "estimate": "15m/30m/1h/2h/4h/8h/2d",
-  "fun": "H/M/L",
-  "blocks": 0,
-  "is_waste": false,
-  "reasoning": "brief explanation of the assessment"
-}
-`, taskDesc))
+// templateSource returns the text of every template in the set, so that changing
+// any of them, shared sections included, shows
+func templateSource(tmpl *template.Template) string {
+	templates := tmpl.Templates()
+	slices.SortFunc(templates, func(a, b *template.Template) int { return strings.Compare(a.Name(), b.Name()) })
 
+	var sb strings.Builder
+	for _, t := range templates {
+		fmt.Fprintf(&sb, "{{define %q}}%s{{end}}", t.Name(), t.Root)
+	}
 	return sb.String()
 }
 
-func parseEnrichmentResponse(response string) (*Enrichment, error) {
-	// Try to extract JSON from the response (in case there's extra text)
-	response = strings.TrimSpace(response)
+// build renders the prompt for one task
+func (p promptBuilder) build(task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (string, error) {
+	return p.render(p.templates().Name(), enrichmentToolName, task, beacons, projects)
+}
+
+// buildSplit renders the prompt asking to break a task into subtasks
+func (p promptBuilder) buildSplit(task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (string, error) {
+	return p.render("split", splitTool.name, task, beacons, projects)
+}
+
+// buildBatch renders the prompt enriching several new tasks in one request
+func (p promptBuilder) buildBatch(tasks []taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (string, error) {
+	data := p.data(batchTool.name, beacons, projects)
+	for _, t := range tasks {
+		data.Tasks = append(data.Tasks, t.Description)
+	}
+	// One search over all of them picks the examples closest to the list as a whole
+	data.Examples = p.examples.examples(strings.Join(data.Tasks, "\n"), projects)
+	return execute(p.templates(), "batch", data)
+}
+
+// render executes the template called name for one task
+func (p promptBuilder) render(name, schema string, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (string, error) {
+	data := p.data(schema, beacons, projects)
+	data.Task = task.Description
+	data.Known = knownFields(task)
+	data.Examples = p.examples.examples(task.Description, projects)
+	return execute(p.templates(), name, data)
+}
+
+// data is the context every prompt gets, whatever the tasks
+func (p promptBuilder) data(schema string, beacons []config.Beacon, projects []config.Project) PromptData {
+	now := time.Now
+	if p.now != nil {
+		now = p.now
+	}
+	cfg := p.cfg
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+
+	return PromptData{
+		Beacons:     beacons,
+		Projects:    projects,
+		UDAs:        cfg.UDAValues,
+		Calendar:    promptCalendar(cfg.Calendar, now()),
+		Preferences: p.preferences,
+		Schema:      schema,
+	}
+}
+
+func execute(tmpl *template.Template, name string, data PromptData) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.ExecuteTemplate(&sb, name, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+	return sb.String(), nil
+}
+
+func promptCalendar(cal config.Calendar, now time.Time) PromptCalendar {
+	now = now.In(cal.Location())
+
+	zone := now.Format("MST")
+	if cal.Timezone != "" {
+		zone = cal.Timezone
+	}
+	c := PromptCalendar{Today: now, Zone: zone, WorkingDays: cal.Workdays()}
 
-	// Find JSON object boundaries
-	start := strings.Index(response, "{")
-	end := strings.LastIndex(response, "}")
+	for i := 1; i <= calendarHorizon; i++ {
+		day := now.AddDate(0, 0, i)
+		c.Upcoming = append(c.Upcoming, CalendarDay{Date: day, Working: cal.IsWorkingDay(day)})
+	}
+
+	today := now.Format("2006-01-02")
+	limit := now.AddDate(1, 0, 0).Format("2006-01-02")
+	for _, h := range cal.Holidays {
+		if h.Date >= today && h.Date <= limit {
+			c.Holidays = append(c.Holidays, h)
+		}
+	}
+	return c
+}
+
+// RenderPrompt returns the prompt tg would send to enrich taskDesc, e.g. for checking a template
+func RenderPrompt(cfg *config.Config, taskDesc string) (string, error) {
+	p, err := newPromptBuilder(cfg)
+	if err != nil {
+		return "", err
+	}
+	return p.build(taskwarrior.Task{Description: taskDesc}, cfg.Beacons, cfg.Projects)
+}
 
-	if start == -1 || end == -1 || end <= start {
-		return nil, fmt.Errorf("no valid JSON found in response: %s", response)
+// decodeReply decodes a structured-output reply, such as an Enrichment.
+// Unknown fields are rejected so schema drift shows up as a ParseError.
+func decodeReply[T any](provider, raw string) (*T, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, &ParseError{Provider: provider, Raw: raw, Err: errors.New("empty reply")}
 	}
 
-	jsonStr := response[start : end+1]
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.DisallowUnknownFields()
 
-	var enrichment Enrichment
-	if err := json.Unmarshal([]byte(jsonStr), &enrichment); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w\nResponse: %s", err, jsonStr)
+	var reply T
+	if err := dec.Decode(&reply); err != nil {
+		return nil, &ParseError{Provider: provider, Raw: raw, Err: err}
 	}
 
-	return &enrichment, nil
+	return &reply, nil
 }
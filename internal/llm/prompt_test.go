@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/taskwarrior"
+)
+
+func TestCustomPrompt(t *testing.T) {
+	now := func() time.Time { return time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC) }
+	beacons := []config.Beacon{{Name: "Health", Tag: "b.health"}}
+	task := taskwarrior.Task{Description: "Book a dentist appointment"}
+
+	tests := []struct {
+		name        string
+		custom      string
+		wantEnrich  string // in the enrichment prompt
+		wantBatch   string // in the batch prompt
+		wantSplit   string // in the split prompt
+		keepsEnrich bool   // the enrichment prompt is still the built-in one
+	}{
+		{
+			name:        "section",
+			custom:      `{{define "calendar"}}## Calendar{{"\n"}}Pretend it is always Monday.{{end}}`,
+			wantEnrich:  "Pretend it is always Monday.",
+			wantBatch:   "Pretend it is always Monday.",
+			keepsEnrich: true,
+		},
+		{
+			name:       "body",
+			custom:     `Tag "{{.Task}}" with {{template "beacons" .}}`,
+			wantEnrich: `Tag "Book a dentist appointment" with `,
+			wantBatch:  "## Tasks to Analyze",
+			wantSplit:  "## Task to Break Down",
+		},
+		{
+			name:        "split",
+			custom:      `{{define "split"}}Split "{{.Task}}" in two{{end}}`,
+			wantBatch:   "## Tasks to Analyze",
+			wantSplit:   `Split "Book a dentist appointment" in two`,
+			keepsEnrich: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parsePrompt("custom.tmpl", tt.custom)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := promptBuilder{tmpl: tmpl, now: now}
+
+			enrich, err := p.build(task, beacons, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			batch, err := p.buildBatch([]taskwarrior.Task{task}, beacons, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			split, err := p.buildSplit(task, beacons, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, c := range []struct{ prompt, rendered, want string }{
+				{"enrichment", enrich, tt.wantEnrich},
+				{"batch", batch, tt.wantBatch},
+				{"split", split, tt.wantSplit},
+			} {
+				if !strings.Contains(c.rendered, c.want) {
+					t.Errorf("%s prompt doesn't contain %q:\n%s", c.prompt, c.want, c.rendered)
+				}
+			}
+			if got := strings.Contains(enrich, "## Task to Analyze"); got != tt.keepsEnrich {
+				t.Errorf("enrichment prompt is the built-in one = %v, want %v", got, tt.keepsEnrich)
+			}
+			if !strings.Contains(enrich, "**Health** (`b.health`)") {
+				t.Errorf("enrichment prompt lost the beacons section:\n%s", enrich)
+			}
+		})
+	}
+}
+
+func TestTemplateSourceCoversSections(t *testing.T) {
+	custom, err := parsePrompt("prompt", `{{define "preferences"}}No preferences.{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if templateSource(custom) == templateSource(defaultPrompt) {
+		t.Error("redefining a section left the template source, and so the cache key, unchanged")
+	}
+	again, err := parsePrompt("prompt", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if templateSource(again) != templateSource(defaultPrompt) {
+		t.Error("the template source of the same set differs between parses")
+	}
+}
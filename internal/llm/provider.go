@@ -2,55 +2,167 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
 
+	"github.com/bf/tg/internal/cache"
 	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/taskwarrior"
 )
 
-// Enrichment represents the LLM's suggestions for a task
+// Enrichment represents the LLM's suggestions for a task.
+// The desc tags end up in the JSON schema sent to the providers.
 type Enrichment struct {
-	Description string   `json:"description"`
-	Beacons     []string `json:"beacons"`     // e.g., ["b.great.dev", "b.organized"]
-	Directions  []string `json:"directions"`  // e.g., ["d.sw.design", "d.test.write"]
-	Project     string   `json:"project"`
-	Priority    string   `json:"priority"`    // H, M, L or empty
-	Due         string   `json:"due"`         // Hard deadline (taskwarrior due format or empty)
-	Scheduled   string   `json:"scheduled"`   // Soft due date - when you'd prefer to do it
-	Effort      string   `json:"effort"`      // E (easy), N (normal), D (difficult)
-	Impact      string   `json:"impact"`      // H (high), M (medium), L (low)
-	Estimate    string   `json:"estimate"`    // 15m, 30m, 1h, 2h, 4h, 8h, 2d
-	Fun         string   `json:"fun"`         // H (high/fun), M (medium), L (low/boring)
-	Blocks      int      `json:"blocks"`      // Number of things/people this task unblocks
-	IsWaste     bool     `json:"is_waste"`    // true if task doesn't align with any beacon
-	Reasoning   string   `json:"reasoning"`   // explanation for the enrichment
+	Description string   `json:"description" desc:"Improved, actionable task description, or the original if no improvement is needed"`
+	Beacons     []string `json:"beacons" desc:"Beacon tags this task contributes to, e.g. b.great.dev"`
+	Directions  []string `json:"directions" desc:"Direction tags within the chosen beacons, e.g. d.sw.design"`
+	Project     string   `json:"project" desc:"Matching project name, or empty string"`
+	Priority    string   `json:"priority" desc:"H, M, L or empty string"`
+	Due         string   `json:"due" desc:"Hard deadline as an absolute ISO date (e.g. 2024-12-01, or 2024-12-01T14:00 when the time matters) or empty string"`
+	Scheduled   string   `json:"scheduled" desc:"Soft due date - when you'd prefer to work on it, as an absolute ISO date (e.g. 2024-11-25) or empty string"`
+	Effort      string   `json:"effort" desc:"E (easy), N (normal) or D (difficult)"`
+	Impact      string   `json:"impact" desc:"H (high), M (medium) or L (low)"`
+	Estimate    string   `json:"estimate" desc:"One of 15m, 30m, 1h, 2h, 4h, 8h, 2d"`
+	Fun         string   `json:"fun" desc:"H (fun), M (neutral) or L (boring)"`
+	Blocks      int      `json:"blocks" desc:"Number of things or people this task unblocks"`
+	IsWaste     bool     `json:"is_waste" desc:"true if the task doesn't align with any beacon"`
+	Reasoning   string   `json:"reasoning" desc:"Brief explanation of the assessment"`
+	Checklist   []string `json:"checklist" desc:"Sub-steps or acceptance criteria worth noting on the task, in order; empty for a single-step task"`
+	Links       []string `json:"links" desc:"URLs mentioned in the task, verbatim; empty when there are none"`
+	Confidence  float64  `json:"confidence" desc:"How confident you are in this assessment, from 0 (guess) to 1 (certain)"`
+
+	Cached   bool     `json:"-"` // served from the local cache instead of the LLM
+	Provider string   `json:"-"` // the fallback chain provider or offline rules that produced it
+	Failed   []string `json:"-"` // providers tried before it, with why they failed
+	Usage    Usage    `json:"-"` // what the LLM calls behind it took; zero when cached
+}
+
+// Annotation is a note tg add can attach to the new task
+type Annotation struct {
+	Kind string // "step", "reasoning" or "link"
+	Text string // the annotation as taskwarrior stores it
+}
+
+// Annotations returns the checklist, the reasoning and the links as annotations
+func (e *Enrichment) Annotations() []Annotation {
+	var annotations []Annotation
+	for _, step := range e.Checklist {
+		annotations = append(annotations, Annotation{Kind: "step", Text: "[ ] " + step})
+	}
+	if e.Reasoning != "" {
+		annotations = append(annotations, Annotation{Kind: "reasoning", Text: "Reasoning: " + e.Reasoning})
+	}
+	for _, link := range e.Links {
+		annotations = append(annotations, Annotation{Kind: "link", Text: link})
+	}
+	return annotations
 }
 
-// Provider is the interface for LLM backends
+// Provider is the interface for LLM backends. Enrich gets the whole task, so what
+// it already has set (say by bugwarrior) is context for the model rather than lost;
+// a new task only has its description.
 type Provider interface {
-	Enrich(ctx context.Context, taskDesc string, beacons []config.Beacon, projects []config.Project) (*Enrichment, error)
+	Enrich(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Enrichment, error)
+	// EnrichBatch enriches several new tasks in as few requests as it can, e.g. for
+	// tg add --file. The enrichments are in the order of tasks.
+	EnrichBatch(ctx context.Context, tasks []taskwarrior.Task, beacons []config.Beacon, projects []config.Project) ([]*Enrichment, error)
+	// Split breaks the task into subtasks, e.g. for tg add --split
+	Split(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Split, error)
 }
 
-// New creates a new LLM provider based on config
-func New(cfg *config.Config) (Provider, error) {
+// New creates a new LLM provider based on config, wrapped in the enrichment cache
+// unless it is disabled. When the provider's API key isn't set it falls back to the
+// offline rules. Every LLM call it makes is recorded by meter.
+func New(cfg *config.Config, meter *Meter) (Provider, error) {
+	prompt, err := newPromptBuilder(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var provider Provider
+	identity := []string{cfg.LLM.Provider, cfg.LLM.Model}
+	if len(cfg.LLM.Providers) > 0 {
+		provider, err = newChain(cfg, prompt, meter)
+		identity = nil
+		for _, p := range cfg.LLM.Providers {
+			identity = append(identity, p.Provider, p.Model)
+		}
+	} else {
+		provider, err = newBackend(cfg, prompt, meter)
+	}
+
+	// Without an API key, e.g. offline on a plane, enrich from the rules instead
+	var keyErr *MissingKeyError
+	if errors.As(err, &keyErr) {
+		return NewRules(cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Cache.Disabled || cfg.LLM.Provider == rulesProviderName && len(cfg.LLM.Providers) == 0 {
+		return provider, nil
+	}
+
+	store, err := OpenCache(cfg)
+	if err != nil {
+		// Enrichment still works without a cache, just slower and costlier
+		return provider, nil
+	}
+	return newCached(provider, store, identity, prompt), nil
+}
+
+// OpenCache opens the enrichment cache under tg's cache directory
+func OpenCache(cfg *config.Config) (*cache.Store, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return cache.Open(filepath.Join(dir, "enrichments"), cfg.Cache.TTL)
+}
+
+func newBackend(cfg *config.Config, prompt promptBuilder, meter *Meter) (Provider, error) {
 	switch cfg.LLM.Provider {
 	case "anthropic":
 		apiKey := cfg.GetAPIKey()
 		if apiKey == "" {
-			return nil, fmt.Errorf("ANTHROPIC_API_KEY not set (configured env: %s)", cfg.LLM.APIKeyEnv)
+			return nil, &MissingKeyError{Provider: "anthropic", Env: cfg.LLM.APIKeyEnv}
 		}
-		return NewAnthropic(apiKey, cfg.LLM.Model), nil
+		p := NewAnthropic(apiKey, cfg.LLM.Model)
+		p.retry = newRetryPolicy(cfg, meter)
+		p.prompt = prompt
+		return p, nil
 	case "openai":
+		if cfg.LLM.Deployment != "" && cfg.LLM.BaseURL == "" {
+			return nil, fmt.Errorf("llm.deployment needs llm.base_url, e.g. https://<resource>.openai.azure.com")
+		}
+		// Self-hosted OpenAI-compatible endpoints may not need a key
 		apiKey := cfg.GetAPIKey()
-		if apiKey == "" {
-			return nil, fmt.Errorf("OPENAI_API_KEY not set (configured env: %s)", cfg.LLM.APIKeyEnv)
+		if apiKey == "" && cfg.LLM.BaseURL == "" {
+			return nil, &MissingKeyError{Provider: "openai", Env: cfg.LLM.APIKeyEnv}
+		}
+		p := NewOpenAI(apiKey, cfg.LLM.Model)
+		if cfg.LLM.BaseURL != "" {
+			p.baseURL = cfg.LLM.BaseURL
 		}
-		return NewOpenAI(apiKey, cfg.LLM.Model), nil
+		p.apiVersion = cfg.LLM.APIVersion
+		p.deployment = cfg.LLM.Deployment
+		p.headers = cfg.LLM.Headers
+		p.retry = newRetryPolicy(cfg, meter)
+		p.prompt = prompt
+		return p, nil
 	case "ollama":
 		baseURL := cfg.LLM.BaseURL
 		if baseURL == "" {
 			baseURL = "http://localhost:11434"
 		}
-		return NewOllama(baseURL, cfg.LLM.Model), nil
+		p := NewOllama(baseURL, cfg.LLM.Model)
+		p.retry = newRetryPolicy(cfg, meter)
+		p.prompt = prompt
+		return p, nil
+	case rulesProviderName:
+		return NewRules(cfg)
 	default:
 		return nil, fmt.Errorf("unknown LLM provider: %s", cfg.LLM.Provider)
 	}
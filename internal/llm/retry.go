@@ -0,0 +1,228 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bf/tg/internal/config"
+)
+
+// defaultMaxAttempts is used when llm.max_attempts isn't configured
+const defaultMaxAttempts = 3
+
+// completion is one structured reply from a backend
+type completion struct {
+	raw    string // JSON produced by the model
+	callID string // tool call id, needed to pair feedback with the call (Anthropic)
+	tokens tokenCount
+}
+
+// turn is a rejected attempt that is replayed to the model so it can correct itself
+type turn struct {
+	completion
+	feedback string
+}
+
+// completer is implemented by every HTTP backend. It sends the prompt, followed by
+// any rejected attempts and their feedback, and returns the model's reply in t's schema.
+type completer interface {
+	name() string
+	complete(ctx context.Context, prompt string, t tool, history []turn) (*completion, error)
+}
+
+// Progress reports the state of an in-flight Enrich call
+type Progress struct {
+	Attempt     int
+	MaxAttempts int
+	Fallback    string // the provider a fallback chain moved on to, empty for the first
+}
+
+type progressKey struct{}
+
+// WithProgress returns a context that makes providers report their progress to fn.
+// fn is called from the provider's goroutine and must not block.
+func WithProgress(ctx context.Context, fn func(Progress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func reportProgress(ctx context.Context, p Progress) {
+	if fn, ok := ctx.Value(progressKey{}).(func(Progress)); ok {
+		fn(p)
+	}
+}
+
+// retryPolicy drives the attempt loop shared by the HTTP backends
+type retryPolicy struct {
+	maxAttempts int
+	cfg         *config.Config // validation target; nil only checks that replies decode
+	meter       *Meter         // records each call in the usage log; nil records nothing
+}
+
+func newRetryPolicy(cfg *config.Config, meter *Meter) retryPolicy {
+	attempts := cfg.LLM.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultMaxAttempts
+	}
+	return retryPolicy{maxAttempts: attempts, cfg: cfg, meter: meter}
+}
+
+// enrich asks c for an enrichment until it gets one that decodes and validates.
+// When attempts run out on an invalid reply, the last one is returned for Validate
+// to repair rather than failing the whole call.
+func (r retryPolicy) enrich(ctx context.Context, c completer, prompt string) (*Enrichment, error) {
+	enrichment, spent, err := retry(ctx, r, c, prompt, enrichmentTool, r.check)
+	if enrichment != nil {
+		enrichment.Usage = spent
+	}
+	return enrichment, err
+}
+
+// retry asks c for a reply in t's schema until it gets one that decodes and passes
+// check. Malformed or rejected replies are sent back to the model with the error as
+// a follow-up turn; rate limits and server errors are retried with backoff. When
+// attempts run out after a rejected reply, that reply is returned without an error,
+// even if the last attempt failed with an API error.
+// The usage covers every call made.
+func retry[T any](ctx context.Context, r retryPolicy, c completer, prompt string, t tool, check func(*T) error) (*T, Usage, error) {
+	attempts := max(r.maxAttempts, 1)
+
+	var history []turn
+	var lastInvalid *T
+	var lastErr error
+	var spent Usage
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		reportProgress(ctx, Progress{Attempt: attempt, MaxAttempts: attempts})
+
+		start := time.Now()
+		comp, err := c.complete(ctx, prompt, t, history)
+		if err == nil {
+			spent.Add(r.meter.record(c.name(), comp.tokens, time.Since(start)))
+		}
+		if err != nil {
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || !apiErr.Temporary() || attempt == attempts {
+				return giveUp(lastInvalid, spent, err)
+			}
+			if werr := backoff(ctx, attempt, apiErr.RetryAfter); werr != nil {
+				return giveUp(lastInvalid, spent, err)
+			}
+			lastErr = err
+			continue
+		}
+
+		reply, err := decodeReply[T](c.name(), comp.raw)
+		if err == nil {
+			if err = check(reply); err == nil {
+				return reply, spent, nil
+			}
+			lastInvalid = reply
+		}
+
+		lastErr = err
+		history = append(history, turn{completion: *comp, feedback: r.feedback(t, err)})
+	}
+
+	return giveUp(lastInvalid, spent, lastErr)
+}
+
+// giveUp ends retry: a rejected reply seen along the way is still worth repairing,
+// even when a later attempt failed outright
+func giveUp[T any](lastInvalid *T, spent Usage, err error) (*T, Usage, error) {
+	if lastInvalid != nil {
+		return lastInvalid, spent, nil
+	}
+	return nil, spent, err
+}
+
+// check validates a copy of e and reports the values Validate could only drop
+func (r retryPolicy) check(e *Enrichment) error {
+	if r.cfg == nil {
+		return nil
+	}
+
+	probe := *e
+	probe.Beacons = slices.Clone(e.Beacons)
+	probe.Directions = slices.Clone(e.Directions)
+
+	var dropped []Fix
+	for _, f := range Validate(&probe, r.cfg) {
+		if f.Dropped() {
+			dropped = append(dropped, f)
+		}
+	}
+	if len(dropped) > 0 {
+		return &ValidationError{Fixes: dropped}
+	}
+	return nil
+}
+
+// feedback turns a rejected reply's error into the follow-up message for the model
+func (r retryPolicy) feedback(t tool, err error) string {
+	var sb strings.Builder
+	sb.WriteString("Your previous reply was rejected.\n")
+
+	var valErr *ValidationError
+	if errors.As(err, &valErr) {
+		for _, f := range valErr.Fixes {
+			fmt.Fprintf(&sb, "- %s: %q is not allowed", f.Field, f.From)
+			if allowed := r.allowed(f.Field); len(allowed) > 0 {
+				fmt.Fprintf(&sb, ", use one of: %s", strings.Join(allowed, ", "))
+			}
+			sb.WriteString("\n")
+		}
+	} else {
+		fmt.Fprintf(&sb, "Error: %v\n", err)
+	}
+
+	fmt.Fprintf(&sb, "Call %s again with corrected values.", t.name)
+	return sb.String()
+}
+
+func (r retryPolicy) allowed(field string) []string {
+	beacons, directions, _ := tagSets(r.cfg.Beacons)
+	switch field {
+	case "beacons":
+		return beacons
+	case "directions":
+		return directions
+	case "priority":
+		return r.cfg.UDAValues.Priority
+	case "effort":
+		return r.cfg.UDAValues.Effort
+	case "impact":
+		return r.cfg.UDAValues.Impact
+	case "estimate":
+		return r.cfg.UDAValues.Estimate
+	case "fun":
+		return r.cfg.UDAValues.Fun
+	}
+	return nil
+}
+
+// backoff waits before the next attempt: Retry-After when the API sent one,
+// exponential otherwise. It gives up early if the wait would outlive ctx.
+func backoff(ctx context.Context, attempt int, retryAfter time.Duration) error {
+	delay := retryAfter
+	if delay <= 0 {
+		delay = time.Second << (attempt - 1)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return context.DeadlineExceeded
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
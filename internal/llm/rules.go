@@ -0,0 +1,205 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/similar"
+	"github.com/bf/tg/internal/taskwarrior"
+)
+
+const rulesProviderName = "rules"
+
+// urlPattern finds http(s) URLs in a description
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// Rules enriches tasks offline from deterministic signals: the project from
+// Project.Keywords, tags from the configured regex rules, due and scheduled dates from
+// phrases like "by friday", and estimate and effort from keyword tables. It needs no
+// API key or network, at the price of knowing nothing about what the task means.
+type Rules struct {
+	tags     []tagRule
+	estimate []config.KeywordRule
+	effort   []config.KeywordRule
+	now      func() time.Time
+}
+
+type tagRule struct {
+	pattern *regexp.Regexp
+	config.TagRule
+}
+
+// rulesMaxConfidence caps the confidence of heuristic results, so tg enrich --auto
+// leaves them for review with the default threshold
+const rulesMaxConfidence = 0.5
+
+func NewRules(cfg *config.Config) (*Rules, error) {
+	r := &Rules{estimate: cfg.Rules.Estimate, effort: cfg.Rules.Effort, now: time.Now}
+	for _, t := range cfg.Rules.Tags {
+		pattern, err := regexp.Compile(t.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rules.tags pattern %q: %w", t.Pattern, err)
+		}
+		r.tags = append(r.tags, tagRule{pattern: pattern, TagRule: t})
+	}
+	return r, nil
+}
+
+func (r *Rules) Enrich(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Enrichment, error) {
+	taskDesc := task.Description
+	e := &Enrichment{Description: taskDesc, Provider: rulesProviderName}
+	var reasons []string
+
+	if project, keyword := detectProject(taskDesc, projects); project != "" {
+		e.Project = project
+		reasons = append(reasons, fmt.Sprintf("project from keyword %q", keyword))
+	}
+
+	for _, t := range r.tags {
+		if !t.pattern.MatchString(taskDesc) {
+			continue
+		}
+		for _, tag := range t.Beacons {
+			if !slices.Contains(e.Beacons, tag) {
+				e.Beacons = append(e.Beacons, tag)
+			}
+		}
+		for _, tag := range t.Directions {
+			if !slices.Contains(e.Directions, tag) {
+				e.Directions = append(e.Directions, tag)
+			}
+		}
+		reasons = append(reasons, fmt.Sprintf("tags from /%s/", t.Pattern))
+	}
+
+	now := r.now()
+	if due, phrase, ok := findDate(taskDesc, dueTriggers, now); ok {
+		e.Due = taskwarrior.DateArg(due)
+		reasons = append(reasons, fmt.Sprintf("due from %q", phrase))
+	}
+	if scheduled, phrase, ok := findDate(taskDesc, scheduledTriggers, now); ok {
+		e.Scheduled = taskwarrior.DateArg(scheduled)
+		reasons = append(reasons, fmt.Sprintf("scheduled from %q", phrase))
+	}
+
+	words := similar.Tokens(taskDesc)
+	if value, keyword, ok := matchKeywords(r.estimate, words); ok {
+		e.Estimate = value
+		reasons = append(reasons, fmt.Sprintf("estimate from %q", keyword))
+	}
+	if value, keyword, ok := matchKeywords(r.effort, words); ok {
+		e.Effort = value
+		reasons = append(reasons, fmt.Sprintf("effort from %q", keyword))
+	}
+
+	for _, link := range urlPattern.FindAllString(taskDesc, -1) {
+		e.Links = append(e.Links, strings.TrimRight(link, ".,;:!?)]"))
+	}
+
+	e.Confidence = min(float64(len(reasons))/10, rulesMaxConfidence)
+	if len(reasons) == 0 {
+		e.Reasoning = "Offline rules: no rule matched this task"
+	} else {
+		e.Reasoning = "Offline rules: " + strings.Join(reasons, ", ")
+	}
+	return e, nil
+}
+
+// EnrichBatch applies the rules to each task; there is no request to save
+func (r *Rules) EnrichBatch(ctx context.Context, tasks []taskwarrior.Task, beacons []config.Beacon, projects []config.Project) ([]*Enrichment, error) {
+	enrichments := make([]*Enrichment, len(tasks))
+	for i, task := range tasks {
+		e, err := r.Enrich(ctx, task, beacons, projects)
+		if err != nil {
+			return nil, err
+		}
+		enrichments[i] = e
+	}
+	return enrichments, nil
+}
+
+func (r *Rules) Split(ctx context.Context, task taskwarrior.Task, beacons []config.Beacon, projects []config.Project) (*Split, error) {
+	return nil, ErrSplitUnsupported
+}
+
+// matchKeywords returns the value of the first rule with a keyword among words.
+// Keywords are normalized like descriptions, so "emails" matches "email".
+func matchKeywords(rules []config.KeywordRule, words []string) (string, string, bool) {
+	for _, rule := range rules {
+		for _, keyword := range rule.Keywords {
+			tokens := similar.Tokens(keyword)
+			if len(tokens) > 0 && !slices.ContainsFunc(tokens, func(t string) bool { return !slices.Contains(words, t) }) {
+				return rule.Value, keyword, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+var (
+	// dueTriggers introduce a deadline: "by friday", "due tomorrow", "before 2025-03-01"
+	dueTriggers = []string{"by", "due", "before", "until", "deadline"}
+	// scheduledTriggers introduce the day to work on it: "on monday", "next tuesday"
+	scheduledTriggers = []string{"on", "next", "this", "tomorrow", "today", "tonight"}
+)
+
+// dateAliases rewrite spoken phrases into the date synonyms ParseDate knows
+var dateAliases = map[string]string{
+	"end of day":        "eod",
+	"end of the day":    "eod",
+	"tonight":           "eod",
+	"end of week":       "eoww",
+	"end of the week":   "eoww",
+	"end of this week":  "eoww",
+	"end of month":      "eom",
+	"end of the month":  "eom",
+	"end of this month": "eom",
+	"end of year":       "eoy",
+	"end of the year":   "eoy",
+	"end of this year":  "eoy",
+	"next week":         "sow",
+	"next month":        "som",
+	"next year":         "soy",
+}
+
+// findDate finds the first trigger word followed by a date phrase of up to four
+// words and returns the date and the phrase. The words today, tomorrow and tonight
+// are their own phrase. Past dates are ignored.
+func findDate(desc string, triggers []string, now time.Time) (time.Time, string, bool) {
+	words := strings.FieldsFunc(strings.ToLower(desc), func(r rune) bool {
+		return r == ' ' || r == ',' || r == ';' || r == '(' || r == ')' || r == '!' || r == '?'
+	})
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	for i, w := range words {
+		if !slices.Contains(triggers, w) {
+			continue
+		}
+		start := i + 1
+		if w == "today" || w == "tomorrow" || w == "tonight" || w == "next" || w == "this" {
+			// The trigger is part of the phrase: "tomorrow", "next friday"
+			start = i
+		}
+		for n := min(len(words)-start, 4); n >= 1; n-- {
+			phrase := strings.Trim(strings.Join(words[start:start+n], " "), ".")
+			expr := phrase
+			if alias, ok := dateAliases[expr]; ok {
+				expr = alias
+			}
+			expr = strings.TrimPrefix(strings.TrimPrefix(expr, "next "), "this ")
+			if expr == "now" || expr == "later" || expr == "someday" {
+				continue
+			}
+			t, ok := taskwarrior.ParseDate(expr, now, taskwarrior.WeekStart())
+			if ok && !t.Before(today) {
+				return t, phrase, true
+			}
+		}
+	}
+	return time.Time{}, "", false
+}
@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"reflect"
+	"strings"
+)
+
+// enrichmentToolName is the tool/schema name the model is asked to fill in
+const enrichmentToolName = "record_enrichment"
+
+// enrichmentSchema is the JSON schema sent to providers that support structured output
+var enrichmentSchema = jsonSchema(reflect.TypeOf(Enrichment{}))
+
+// tool is a structured reply the model is asked for: a forced tool call for Anthropic,
+// a JSON schema response format for OpenAI and Ollama
+type tool struct {
+	name        string
+	description string
+	schema      map[string]any
+	maxTokens   int // reply budget, for APIs that require one (Anthropic)
+}
+
+var enrichmentTool = tool{name: enrichmentToolName, description: "Record the task enrichment", schema: enrichmentSchema, maxTokens: 2048}
+
+// jsonSchema derives a JSON schema from a Go type, using the json tag for property
+// names and the desc tag for descriptions. Every property is required and no extra
+// properties are allowed, which is what OpenAI's strict mode expects.
+func jsonSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			prop := jsonSchema(field.Type)
+			if desc := field.Tag.Get("desc"); desc != "" {
+				prop["description"] = desc
+			}
+			properties[name] = prop
+			required = append(required, name)
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	default:
+		return map[string]any{}
+	}
+}
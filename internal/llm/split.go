@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/bf/tg/internal/config"
+)
+
+//go:embed split.tmpl
+var splitPromptTemplate string
+
+// Split is a task broken down into subtasks. The desc tags end up in the JSON schema.
+type Split struct {
+	Project   string    `json:"project" desc:"Matching project name for all subtasks, or empty string"`
+	Subtasks  []Subtask `json:"subtasks" desc:"The subtasks, in the order they should be done"`
+	Reasoning string    `json:"reasoning" desc:"Brief explanation of the breakdown"`
+
+	Provider string `json:"-"` // the fallback chain provider that produced it
+	Usage    Usage  `json:"-"`
+}
+
+// Subtask is one step of a Split
+type Subtask struct {
+	Description string   `json:"description" desc:"Actionable description of the subtask"`
+	Beacons     []string `json:"beacons" desc:"Beacon tags this subtask contributes to, e.g. b.great.dev"`
+	Directions  []string `json:"directions" desc:"Direction tags within the chosen beacons, e.g. d.sw.design"`
+	Effort      string   `json:"effort" desc:"E (easy), N (normal) or D (difficult)"`
+	Estimate    string   `json:"estimate" desc:"One of 15m, 30m, 1h, 2h, 4h, 8h, 2d"`
+	DependsOn   []int    `json:"depends_on" desc:"Numbers of the earlier subtasks (1 is the first) that must be done before this one; empty if it can start right away"`
+}
+
+var splitTool = tool{name: "record_subtasks", description: "Record the subtasks of the task", schema: jsonSchema(reflect.TypeOf(Split{})), maxTokens: 2048}
+
+// maxSubtasks keeps a split to a list someone would actually work through
+const maxSubtasks = 8
+
+// ErrSplitUnsupported is returned by providers that can't break tasks down
+var ErrSplitUnsupported = errors.New("the offline rules can't split tasks, set an API key for a provider")
+
+// split asks c for subtasks until it gets at least two
+func (r retryPolicy) split(ctx context.Context, c completer, prompt string) (*Split, error) {
+	s, spent, err := retry(ctx, r, c, prompt, splitTool, checkSplit)
+	if s != nil {
+		s.Usage = spent
+	}
+	return s, err
+}
+
+func checkSplit(s *Split) error {
+	switch {
+	case len(s.Subtasks) < 2:
+		return fmt.Errorf("got %d subtasks, break the task into at least 2", len(s.Subtasks))
+	case len(s.Subtasks) > maxSubtasks:
+		return fmt.Errorf("got %d subtasks, use at most %d", len(s.Subtasks), maxSubtasks)
+	}
+	return nil
+}
+
+// ValidateSplit repairs each subtask like Validate repairs an enrichment, drops empty
+// subtasks, keeps dependencies pointing at earlier subtasks only and clears a project
+// that isn't configured. Fields of the returned fixes name the subtask, e.g.
+// "subtask 2 effort".
+func ValidateSplit(s *Split, cfg *config.Config) []Fix {
+	var fixes []Fix
+
+	// Numbers shift when empty subtasks are dropped
+	renumber := make(map[int]int)
+	var subtasks []Subtask
+	for i, st := range s.Subtasks {
+		st.Description = strings.TrimSpace(st.Description)
+		if st.Description == "" {
+			continue
+		}
+		subtasks = append(subtasks, st)
+		renumber[i+1] = len(subtasks)
+	}
+	s.Subtasks = subtasks[:min(len(subtasks), maxSubtasks)]
+
+	for i := range s.Subtasks {
+		st := &s.Subtasks[i]
+		n := i + 1
+
+		e := &Enrichment{Beacons: st.Beacons, Directions: st.Directions, Effort: st.Effort, Estimate: st.Estimate}
+		for _, f := range Validate(e, cfg) {
+			f.Field = fmt.Sprintf("subtask %d %s", n, f.Field)
+			fixes = append(fixes, f)
+		}
+		st.Beacons, st.Directions, st.Effort, st.Estimate = e.Beacons, e.Directions, e.Effort, e.Estimate
+
+		var depends []int
+		for _, d := range st.DependsOn {
+			dep, ok := renumber[d]
+			if !ok || dep >= n {
+				fixes = append(fixes, Fix{Field: fmt.Sprintf("subtask %d depends_on", n), From: fmt.Sprint(d)})
+				continue
+			}
+			if !slices.Contains(depends, dep) {
+				depends = append(depends, dep)
+			}
+		}
+		slices.Sort(depends)
+		st.DependsOn = depends
+	}
+
+	if s.Project != "" && !slices.ContainsFunc(cfg.Projects, func(p config.Project) bool { return p.Name == s.Project }) {
+		fixes = append(fixes, Fix{Field: "project", From: s.Project})
+		s.Project = ""
+	}
+	return fixes
+}
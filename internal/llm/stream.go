@@ -0,0 +1,189 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type partialKey struct{}
+
+// WithPartial returns a context that makes providers stream their reply and report
+// the enrichment parsed so far to fn after each chunk. Fields fill in as they arrive
+// and string values may be cut short. fn is called from the provider's goroutine and
+// must not block. Cached results and the offline rules don't stream.
+func WithPartial(ctx context.Context, fn func(*Enrichment)) context.Context {
+	return context.WithValue(ctx, partialKey{}, fn)
+}
+
+// partialStream collects the streamed JSON of one completion
+type partialStream struct {
+	buf strings.Builder
+	fn  func(*Enrichment)
+}
+
+// newPartialStream returns nil when ctx doesn't ask for streaming
+func newPartialStream(ctx context.Context) *partialStream {
+	fn, ok := ctx.Value(partialKey{}).(func(*Enrichment))
+	if !ok {
+		return nil
+	}
+	return &partialStream{fn: fn}
+}
+
+func (s *partialStream) write(chunk string) {
+	if chunk == "" {
+		return
+	}
+	s.buf.WriteString(chunk)
+	if e := ParsePartial(s.buf.String()); e != nil {
+		s.fn(e)
+	}
+}
+
+func (s *partialStream) String() string {
+	return s.buf.String()
+}
+
+// ParsePartial decodes the enrichment from JSON that may be cut off anywhere, by
+// closing the open string, arrays and objects. A member whose key or value is
+// incomplete is dropped. It returns nil until the object has started.
+func ParsePartial(raw string) *Enrichment {
+	start := strings.IndexByte(raw, '{')
+	if start < 0 {
+		return nil
+	}
+	s := raw[start:]
+
+	for s != "" {
+		var e Enrichment
+		err := json.Unmarshal([]byte(closeJSON(s)), &e)
+		var typeErr *json.UnmarshalTypeError
+		if err == nil || errors.As(err, &typeErr) {
+			return &e
+		}
+		// Drop the last member and try again
+		cut := strings.LastIndexAny(s, ",{[")
+		if cut < 0 {
+			break
+		}
+		if s[cut] == ',' || cut == len(s)-1 {
+			s = s[:cut]
+		} else {
+			s = s[:cut+1]
+		}
+	}
+	return nil
+}
+
+// closeJSON appends what it takes to terminate truncated JSON: a closing quote for
+// an open string, null for a key still waiting for its value, and the brackets and
+// braces still open
+func closeJSON(s string) string {
+	var open []byte
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch c {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{':
+			open = append(open, '}')
+		case c == '[':
+			open = append(open, ']')
+		case c == '}' || c == ']':
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		}
+	}
+
+	var sb strings.Builder
+	if inString {
+		if escaped {
+			s = s[:len(s)-1]
+		}
+		// A unicode escape cut short can't be decoded
+		if i := strings.LastIndex(s, `\u`); i >= 0 && len(s)-i < 6 && (i == 0 || s[i-1] != '\\') {
+			s = s[:i]
+		}
+		sb.WriteString(s)
+		sb.WriteByte('"')
+	} else {
+		s = strings.TrimRight(s, " \t\r\n")
+		s = strings.TrimSuffix(s, ",")
+		sb.WriteString(s)
+		if strings.HasSuffix(s, ":") {
+			sb.WriteString("null")
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		sb.WriteByte(open[i])
+	}
+	return sb.String()
+}
+
+// readEvents calls fn with the data of each server-sent event in r, stopping at the
+// OpenAI-style [DONE] marker
+func readEvents(r io.Reader, fn func(data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue // event names, comments and keep-alives
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return nil
+		}
+		if err := fn([]byte(data)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// readLines calls fn with each non-empty line of a newline-delimited JSON stream
+func readLines(r io.Reader, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// newStreamError builds an APIError from an error event sent after the response
+// started with 200, using the status the API gives the same error up front so
+// overloads and rate limits are still retried
+func newStreamError(provider, kind, message string) *APIError {
+	status := http.StatusInternalServerError
+	switch kind {
+	case "overloaded_error":
+		status = 529
+	case "rate_limit_error":
+		status = http.StatusTooManyRequests
+	case "invalid_request_error":
+		status = http.StatusBadRequest
+	}
+	return &APIError{Provider: provider, StatusCode: status, Message: message}
+}
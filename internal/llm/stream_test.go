@@ -0,0 +1,141 @@
+package llm
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCloseJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"complete", `{"a": 1}`, `{"a": 1}`},
+		{"open object", `{"a": 1`, `{"a": 1}`},
+		{"open string", `{"a": "hel`, `{"a": "hel"}`},
+		{"open key", `{"a": 1, "b`, `{"a": 1, "b"}`},
+		{"dangling key", `{"a": 1, "b":`, `{"a": 1, "b":null}`},
+		{"dangling key with space", `{"a": 1, "b": `, `{"a": 1, "b":null}`},
+		{"trailing comma", `{"a": 1,`, `{"a": 1}`},
+		{"trailing comma and newline", "{\"a\": 1,\n  ", `{"a": 1}`},
+		{"open array", `{"a": ["x", "y`, `{"a": ["x", "y"]}`},
+		{"empty array", `{"a": [`, `{"a": []}`},
+		{"nested", `{"a": [{"b": [1, 2`, `{"a": [{"b": [1, 2]}]}`},
+		{"closed array", `{"a": ["x"], "b": "y`, `{"a": ["x"], "b": "y"}`},
+		{"brackets in string", `{"a": "[{`, `{"a": "[{"}`},
+		{"escaped quote", `{"a": "say \"hi`, `{"a": "say \"hi"}`},
+		{"cut escape", `{"a": "say \`, `{"a": "say "}`},
+		{"escaped backslash", `{"a": "c:\\`, `{"a": "c:\\"}`},
+		{"cut unicode escape", `{"a": "caf\u00`, `{"a": "caf"}`},
+		{"cut unicode escape start", `{"a": "caf\u`, `{"a": "caf"}`},
+		{"whole unicode escape", `{"a": "caf\u00e9`, `{"a": "caf\u00e9"}`},
+		{"escaped backslash before u", `{"a": "c:\\u00`, `{"a": "c:\\u00"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := closeJSON(tt.in); got != tt.want {
+				t.Errorf("closeJSON(%s) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePartial(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want Enrichment
+	}{
+		{"key cut short", `{"description": "Call Bob", "proj`, Enrichment{Description: "Call Bob"}},
+		{"dangling key", `{"description": "Call Bob", "project":`, Enrichment{Description: "Call Bob"}},
+		{"string cut short", `{"description": "Call Bob", "project": "ho`, Enrichment{Description: "Call Bob", Project: "ho"}},
+		{"cut escape", `{"description": "Say \`, Enrichment{Description: "Say "}},
+		{"escaped quote", `{"description": "Say \"hi\`, Enrichment{Description: `Say "hi`}},
+		{"cut unicode escape", `{"description": "Caf\u00`, Enrichment{Description: "Caf"}},
+		{"unicode escape", `{"description": "Caf\u00e9`, Enrichment{Description: "Café"}},
+		{"array cut short", `{"beacons": ["b.health", "b.gr`, Enrichment{Beacons: []string{"b.health", "b.gr"}}},
+		{"number cut short", `{"description": "x", "confidence": 0.`, Enrichment{Description: "x"}},
+		{"only number cut short", `{"confidence": 0.`, Enrichment{}},
+		{"bool cut short", `{"description": "x", "is_waste": tr`, Enrichment{Description: "x"}},
+		{"wrong type", `{"description": "x", "blocks": "two"}`, Enrichment{Description: "x"}},
+		{"text before object", `Here you go: {"description": "x"`, Enrichment{Description: "x"}},
+		{"object just started", `{`, Enrichment{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParsePartial(tt.raw)
+			if got == nil {
+				t.Fatalf("ParsePartial(%s) = nil", tt.raw)
+			}
+			if got.Description != tt.want.Description || got.Project != tt.want.Project ||
+				!slices.Equal(got.Beacons, tt.want.Beacons) || got.Confidence != tt.want.Confidence || got.IsWaste != tt.want.IsWaste {
+				t.Errorf("ParsePartial(%s) = %+v, want %+v", tt.raw, *got, tt.want)
+			}
+		})
+	}
+
+	for _, raw := range []string{"", "Here you go:", `"description": "x"`} {
+		if got := ParsePartial(raw); got != nil {
+			t.Errorf("ParsePartial(%q) = %+v, want nil before the object starts", raw, *got)
+		}
+	}
+}
+
+// Every prefix of a reply must parse into fields that are prefixes of the final ones
+func TestParsePartialEveryPrefix(t *testing.T) {
+	raw := `{
+  "description": "Email \"Bob\" about the caf\u00e9 \\ offsite\nplan",
+  "beacons": ["b.great.dev", "b.health"],
+  "directions": [],
+  "project": "work.offsite",
+  "blocks": 3,
+  "is_waste": false,
+  "checklist": ["Draft the email", "Send it"],
+  "confidence": 0.85
+}`
+	var want Enrichment
+	if err := json.Unmarshal([]byte(raw), &want); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= len(raw); i++ {
+		prefix := raw[:i]
+		got := ParsePartial(prefix)
+		if got == nil {
+			t.Fatalf("ParsePartial(%q) = nil", prefix)
+		}
+		if !strings.HasPrefix(want.Description, got.Description) {
+			t.Errorf("ParsePartial(%q) description = %q, not a prefix of %q", prefix, got.Description, want.Description)
+		}
+		if !strings.HasPrefix(want.Project, got.Project) {
+			t.Errorf("ParsePartial(%q) project = %q, not a prefix of %q", prefix, got.Project, want.Project)
+		}
+		if !isPrefixList(got.Beacons, want.Beacons) {
+			t.Errorf("ParsePartial(%q) beacons = %q, not a prefix of %q", prefix, got.Beacons, want.Beacons)
+		}
+		if !isPrefixList(got.Checklist, want.Checklist) {
+			t.Errorf("ParsePartial(%q) checklist = %q, not a prefix of %q", prefix, got.Checklist, want.Checklist)
+		}
+	}
+
+	got := ParsePartial(raw)
+	if got.Description != want.Description || got.Blocks != want.Blocks || got.Confidence != want.Confidence {
+		t.Errorf("ParsePartial(whole reply) = %+v, want %+v", *got, want)
+	}
+}
+
+// isPrefixList reports whether got is a prefix of want whose last item may be cut short
+func isPrefixList(got, want []string) bool {
+	if len(got) > len(want) {
+		return false
+	}
+	for i, s := range got {
+		if s != want[i] && (i < len(got)-1 || !strings.HasPrefix(want[i], s)) {
+			return false
+		}
+	}
+	return true
+}
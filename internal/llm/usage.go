@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/usage"
+)
+
+// Usage is what the LLM calls behind one enrichment took, retries included
+type Usage struct {
+	Calls        int
+	InputTokens  int
+	OutputTokens int
+	Latency      time.Duration
+	Cost         float64 // estimated USD; models without a configured price count as free
+}
+
+// Add counts the calls behind o in u too, e.g. to total a batch
+func (u *Usage) Add(o Usage) {
+	u.Calls += o.Calls
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.Latency += o.Latency
+	u.Cost += o.Cost
+}
+
+// tokenCount is the usage a backend's reply reported
+type tokenCount struct {
+	model         string
+	input, output int
+}
+
+// Meter prices each LLM call, appends it to the usage log under the tg command that
+// made it and keeps the running total, e.g. for tg enrich's budget
+type Meter struct {
+	log     *usage.Log // nil when there is no state directory to keep it in
+	cfg     *config.Config
+	command string
+
+	mu    sync.Mutex
+	spent float64
+}
+
+// NewMeter returns a meter that attributes calls to command, e.g. "enrich"
+func NewMeter(cfg *config.Config, command string) *Meter {
+	m := &Meter{cfg: cfg, command: command}
+	if path, err := usage.DefaultLogPath(); err == nil {
+		m.log = usage.OpenLog(path)
+	}
+	return m
+}
+
+// Spent returns the estimated cost of the calls metered so far, in USD
+func (m *Meter) Spent() float64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.spent
+}
+
+// record logs one call and returns its usage. The log is best effort: a failed
+// write loses the record, not the enrichment, and the cost still counts towards Spent.
+func (m *Meter) record(provider string, tokens tokenCount, latency time.Duration) Usage {
+	u := Usage{Calls: 1, InputTokens: tokens.input, OutputTokens: tokens.output, Latency: latency}
+	if m == nil {
+		return u
+	}
+
+	price, priced := m.cfg.PriceOf(tokens.model)
+	u.Cost = (float64(tokens.input)*price.Input + float64(tokens.output)*price.Output) / 1e6
+
+	m.mu.Lock()
+	m.spent += u.Cost
+	m.mu.Unlock()
+
+	if m.log != nil {
+		m.log.Append(usage.Record{
+			Command:      m.command,
+			Provider:     provider,
+			Model:        tokens.model,
+			InputTokens:  tokens.input,
+			OutputTokens: tokens.output,
+			LatencyMS:    latency.Milliseconds(),
+			Cost:         u.Cost,
+			Unpriced:     !priced,
+		})
+	}
+	return u
+}
@@ -0,0 +1,302 @@
+package llm
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bf/tg/internal/config"
+)
+
+// Fix records one repair Validate made to an Enrichment, or a problem it only flagged
+type Fix struct {
+	Field string // e.g. "beacons", "effort"
+	From  string // value suggested by the LLM
+	To    string // repaired value, empty when the value was dropped
+	Note  string // set for flagged problems that were left unchanged
+}
+
+// Dropped reports whether the invalid value was removed instead of repaired
+func (f Fix) Dropped() bool {
+	return f.Note == "" && f.To == ""
+}
+
+func (f Fix) String() string {
+	switch {
+	case f.Note != "":
+		return fmt.Sprintf("%s: %s %s", f.Field, f.From, f.Note)
+	case f.To == "":
+		return fmt.Sprintf("%s: dropped unknown %q", f.Field, f.From)
+	default:
+		return fmt.Sprintf("%s: %q → %q", f.Field, f.From, f.To)
+	}
+}
+
+// Validate checks an Enrichment against the configured beacons, directions and UDA
+// values. Invalid values are mapped to the closest allowed value when there is an
+// unambiguous one and dropped otherwise; e is repaired in place.
+func Validate(e *Enrichment, cfg *config.Config) []Fix {
+	var fixes []Fix
+
+	beaconTags, directionTags, parents := tagSets(cfg.Beacons)
+
+	// Tags put into the wrong list are moved rather than fuzzy-matched
+	var beacons, directions []string
+	for _, tag := range e.Beacons {
+		if slices.Contains(directionTags, tag) && !slices.Contains(beaconTags, tag) {
+			fixes = append(fixes, Fix{Field: "beacons", From: tag, Note: "is a direction, moved to directions"})
+			directions = append(directions, tag)
+			continue
+		}
+		beacons = append(beacons, tag)
+	}
+	for _, tag := range e.Directions {
+		if slices.Contains(beaconTags, tag) && !slices.Contains(directionTags, tag) {
+			fixes = append(fixes, Fix{Field: "directions", From: tag, Note: "is a beacon, moved to beacons"})
+			beacons = append(beacons, tag)
+			continue
+		}
+		directions = append(directions, tag)
+	}
+
+	e.Beacons, fixes = validateTags("beacons", "b.", beacons, beaconTags, fixes)
+	e.Directions, fixes = validateTags("directions", "d.", directions, directionTags, fixes)
+
+	// A direction only makes sense under one of the beacons it belongs to
+	for _, dir := range e.Directions {
+		hasParent := false
+		for _, parent := range parents[dir] {
+			if slices.Contains(e.Beacons, parent) {
+				hasParent = true
+				break
+			}
+		}
+		if !hasParent {
+			fixes = append(fixes, Fix{
+				Field: "directions",
+				From:  dir,
+				Note:  "has no parent beacon selected (" + strings.Join(parents[dir], ", ") + ")",
+			})
+		}
+	}
+
+	allowed := cfg.UDAValues
+	e.Priority, fixes = validateEnum("priority", e.Priority, allowed.Priority, fixes)
+	e.Effort, fixes = validateEnum("effort", e.Effort, allowed.Effort, fixes)
+	e.Impact, fixes = validateEnum("impact", e.Impact, allowed.Impact, fixes)
+	e.Fun, fixes = validateEnum("fun", e.Fun, allowed.Fun, fixes)
+	e.Estimate, fixes = validateEstimate(e.Estimate, allowed.Estimate, fixes)
+
+	if e.Blocks < 0 {
+		fixes = append(fixes, Fix{Field: "blocks", From: strconv.Itoa(e.Blocks), To: "0"})
+		e.Blocks = 0
+	}
+
+	// Annotations are optional, so anything unusable is dropped without a fix
+	e.Checklist = slices.DeleteFunc(e.Checklist, func(step string) bool { return strings.TrimSpace(step) == "" })
+	e.Links = slices.DeleteFunc(e.Links, func(link string) bool { return !linkPattern.MatchString(link) })
+
+	return fixes
+}
+
+// linkPattern matches a whole http(s) URL
+var linkPattern = regexp.MustCompile(`^https?://[^\s<>"]+$`)
+
+// tagSets returns all beacon tags, all direction tags and the beacons each direction belongs to
+func tagSets(beacons []config.Beacon) ([]string, []string, map[string][]string) {
+	var beaconTags, directionTags []string
+	parents := make(map[string][]string)
+	for _, b := range beacons {
+		beaconTags = append(beaconTags, b.Tag)
+		for _, d := range b.Directions {
+			if _, seen := parents[d.Tag]; !seen {
+				directionTags = append(directionTags, d.Tag)
+			}
+			parents[d.Tag] = append(parents[d.Tag], b.Tag)
+		}
+	}
+	return beaconTags, directionTags, parents
+}
+
+func validateTags(field, prefix string, tags, known []string, fixes []Fix) ([]string, []Fix) {
+	var valid []string
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "+")
+		match := tag
+		if !slices.Contains(known, tag) {
+			match = closestTag(tag, prefix, known)
+			fixes = append(fixes, Fix{Field: field, From: tag, To: match})
+		}
+		if match != "" && !slices.Contains(valid, match) {
+			valid = append(valid, match)
+		}
+	}
+	return valid, fixes
+}
+
+// closestTag maps a hallucinated tag onto a known one, e.g. "b.great.developer" onto
+// "b.great.dev". It returns "" when no candidate is close enough or several tie.
+func closestTag(tag, prefix string, known []string) string {
+	tag = strings.ToLower(tag)
+	if !strings.HasPrefix(tag, prefix) {
+		tag = prefix + tag
+	}
+	if slices.Contains(known, tag) {
+		return tag
+	}
+
+	// Segment-wise prefix match: every dot-separated part of one is a prefix of the other
+	var matches []string
+	for _, k := range known {
+		if segmentsMatch(tag, k) {
+			matches = append(matches, k)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0]
+	}
+
+	// Fall back to edit distance for typos
+	best, bestDist, tie := "", len(tag)/4+1, false
+	for _, k := range known {
+		d := levenshtein(tag, k)
+		switch {
+		case d < bestDist:
+			best, bestDist, tie = k, d, false
+		case d == bestDist && best != "":
+			tie = true
+		}
+	}
+	if tie {
+		return ""
+	}
+	return best
+}
+
+func segmentsMatch(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	if len(as) != len(bs) {
+		return false
+	}
+	for i := range as {
+		if !strings.HasPrefix(as[i], bs[i]) && !strings.HasPrefix(bs[i], as[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// validateEnum accepts an allowed value as-is, maps words like "high" or "easy" onto
+// their single-letter code and drops anything else. Empty means unset and is valid.
+func validateEnum(field, value string, allowed []string, fixes []Fix) (string, []Fix) {
+	value = strings.TrimSpace(value)
+	if value == "" || slices.Contains(allowed, value) {
+		return value, fixes
+	}
+
+	mapped := ""
+	if upper := strings.ToUpper(value); slices.Contains(allowed, upper) {
+		mapped = upper
+	} else if synonym, ok := enumSynonyms[strings.ToLower(value)]; ok && slices.Contains(allowed, synonym) {
+		mapped = synonym
+	} else if isWord(value) {
+		if initial := strings.ToUpper(value[:1]); slices.Contains(allowed, initial) {
+			mapped = initial
+		}
+	}
+
+	return mapped, append(fixes, Fix{Field: field, From: value, To: mapped})
+}
+
+// enumSynonyms covers words whose initial isn't the code they stand for
+var enumSynonyms = map[string]string{
+	"hard":    "D",
+	"neutral": "M",
+	"boring":  "L",
+	"fun":     "H",
+}
+
+func isWord(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// validateEstimate rounds an off-list duration up to the next allowed estimate,
+// in line with the pessimistic estimation the prompt asks for
+func validateEstimate(value string, allowed []string, fixes []Fix) (string, []Fix) {
+	value = strings.TrimSpace(value)
+	if value == "" || slices.Contains(allowed, value) {
+		return value, fixes
+	}
+
+	mapped := ""
+	if minutes, ok := parseEstimate(value); ok {
+		for _, a := range allowed {
+			am, ok := parseEstimate(a)
+			if !ok {
+				continue
+			}
+			mapped = a
+			if am >= minutes {
+				break
+			}
+		}
+	}
+
+	return mapped, append(fixes, Fix{Field: "estimate", From: value, To: mapped})
+}
+
+// parseEstimate converts "45m", "3h", "1 day" etc. to minutes, counting a day as 8h of work
+func parseEstimate(s string) (int, bool) {
+	s = strings.ToLower(strings.ReplaceAll(s, " ", ""))
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, false
+	}
+
+	var unit float64
+	switch strings.TrimSuffix(s[i:], "s") {
+	case "m", "min", "minute":
+		unit = 1
+	case "h", "hr", "hour":
+		unit = 60
+	case "d", "day":
+		unit = 8 * 60
+	case "w", "week":
+		unit = 5 * 8 * 60
+	default:
+		return 0, false
+	}
+	return int(n * unit), true
+}
@@ -0,0 +1,164 @@
+package llm
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bf/tg/internal/config"
+)
+
+func testValidateConfig() *config.Config {
+	return &config.Config{
+		Beacons: []config.Beacon{
+			{Tag: "b.great.dev", Directions: []config.Direction{{Tag: "d.sw.design"}}},
+			{Tag: "b.health", Directions: []config.Direction{{Tag: "d.sleep"}}},
+			{Tag: "b.family"},
+		},
+		UDAValues: config.UDAValues{
+			Priority: []string{"H", "M", "L"},
+			Effort:   []string{"E", "N", "D"},
+			Impact:   []string{"H", "M", "L"},
+			Estimate: []string{"15m", "30m", "1h", "2h", "4h", "8h", "2d"},
+			Fun:      []string{"H", "M", "L"},
+		},
+	}
+}
+
+func TestClosestTag(t *testing.T) {
+	beacons := []string{"b.great.dev", "b.health", "b.family"}
+	tests := []struct {
+		name   string
+		tag    string
+		prefix string
+		known  []string
+		want   string
+	}{
+		{"exact", "b.health", "b.", beacons, "b.health"},
+		{"case", "B.Health", "b.", beacons, "b.health"},
+		{"missing prefix", "great.dev", "b.", beacons, "b.great.dev"},
+		{"longer segment", "b.great.developer", "b.", beacons, "b.great.dev"},
+		{"shorter segment", "b.fam", "b.", beacons, "b.family"},
+		{"typo", "b.helth", "b.", beacons, "b.health"},
+		{"too far", "b.xyz", "b.", beacons, ""},
+		{"segment prefix ambiguous", "d.sw.de", "d.", []string{"d.sw.design", "d.sw.debug"}, ""},
+		{"typo tie", "d.sw.cax", "d.", []string{"d.sw.cat", "d.sw.car"}, ""},
+		{"segment count differs", "b.great", "b.", []string{"b.great.dev"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := closestTag(tt.tag, tt.prefix, tt.known); got != tt.want {
+				t.Errorf("closestTag(%q) = %q, want %q", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateEstimate(t *testing.T) {
+	allowed := testValidateConfig().UDAValues.Estimate
+	tests := []struct {
+		value   string
+		want    string
+		wantFix bool
+	}{
+		{"", "", false},
+		{"1h", "1h", false},
+		{"10m", "15m", true},
+		{"45m", "1h", true},
+		{"1.5h", "2h", true},
+		{"3h", "4h", true},
+		{"1 day", "8h", true},
+		{"3d", "2d", true},
+		{"1 week", "2d", true},
+		{"soon", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, fixes := validateEstimate(tt.value, allowed, nil)
+			if got != tt.want {
+				t.Errorf("validateEstimate(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+			if (len(fixes) > 0) != tt.wantFix {
+				t.Errorf("validateEstimate(%q) fixes = %v, want fix %v", tt.value, fixes, tt.wantFix)
+			}
+		})
+	}
+}
+
+func TestValidateEnum(t *testing.T) {
+	tests := []struct {
+		value   string
+		allowed []string
+		want    string
+	}{
+		{"", []string{"H", "M", "L"}, ""},
+		{"M", []string{"H", "M", "L"}, "M"},
+		{"m", []string{"H", "M", "L"}, "M"},
+		{"high", []string{"H", "M", "L"}, "H"},
+		{"easy", []string{"E", "N", "D"}, "E"},
+		{"hard", []string{"E", "N", "D"}, "D"},
+		{"boring", []string{"H", "M", "L"}, "L"},
+		{"urgent", []string{"H", "M", "L"}, ""},
+		{"3", []string{"H", "M", "L"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got, _ := validateEnum("field", tt.value, tt.allowed, nil); got != tt.want {
+				t.Errorf("validateEnum(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	e := &Enrichment{
+		Beacons:    []string{"b.great.developer", "d.sleep"},
+		Directions: []string{"d.sw.design"},
+		Priority:   "high",
+		Estimate:   "45m",
+		Blocks:     -2,
+		Checklist:  []string{"write it", "  "},
+		Links:      []string{"https://example.com/x", "not a link"},
+	}
+
+	fixes := Validate(e, testValidateConfig())
+
+	wantFixes := []Fix{
+		{Field: "beacons", From: "d.sleep", Note: "is a direction, moved to directions"},
+		{Field: "beacons", From: "b.great.developer", To: "b.great.dev"},
+		{Field: "directions", From: "d.sleep", Note: "has no parent beacon selected (b.health)"},
+		{Field: "priority", From: "high", To: "H"},
+		{Field: "estimate", From: "45m", To: "1h"},
+		{Field: "blocks", From: "-2", To: "0"},
+	}
+	if !slices.Equal(fixes, wantFixes) {
+		t.Errorf("fixes = %v\nwant %v", fixes, wantFixes)
+	}
+
+	if want := []string{"b.great.dev"}; !slices.Equal(e.Beacons, want) {
+		t.Errorf("beacons = %v, want %v", e.Beacons, want)
+	}
+	if want := []string{"d.sleep", "d.sw.design"}; !slices.Equal(e.Directions, want) {
+		t.Errorf("directions = %v, want %v", e.Directions, want)
+	}
+	if e.Priority != "H" || e.Estimate != "1h" || e.Blocks != 0 {
+		t.Errorf("priority, estimate, blocks = %q, %q, %d", e.Priority, e.Estimate, e.Blocks)
+	}
+	if want := []string{"write it"}; !slices.Equal(e.Checklist, want) {
+		t.Errorf("checklist = %v, want %v", e.Checklist, want)
+	}
+	if want := []string{"https://example.com/x"}; !slices.Equal(e.Links, want) {
+		t.Errorf("links = %v, want %v", e.Links, want)
+	}
+}
+
+func TestValidateDropsUnknownTag(t *testing.T) {
+	e := &Enrichment{Beacons: []string{"b.xyz", "+b.health", "b.health"}}
+	fixes := Validate(e, testValidateConfig())
+
+	if want := []string{"b.health"}; !slices.Equal(e.Beacons, want) {
+		t.Errorf("beacons = %v, want %v", e.Beacons, want)
+	}
+	if len(fixes) != 1 || !fixes[0].Dropped() || fixes[0].From != "b.xyz" {
+		t.Errorf("fixes = %v, want b.xyz dropped", fixes)
+	}
+}
@@ -0,0 +1,160 @@
+// Package similar finds texts that resemble each other by their normalized words,
+// e.g. past tasks worth showing the LLM as examples or likely duplicates of a new task.
+package similar
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// stopwords carry no meaning for matching task descriptions
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "in": true, "into": true, "is": true, "it": true,
+	"of": true, "on": true, "or": true, "our": true, "the": true, "this": true, "to": true,
+	"up": true, "with": true, "my": true, "we": true, "i": true,
+}
+
+// Tokens splits s into lowercase words, dropping stopwords and trailing plural s, so
+// "Update the READMEs" and "update readme" yield the same tokens. Hyphenated words
+// such as ticket keys (JIRA-123) are kept whole.
+func Tokens(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+
+	var tokens []string
+	for _, f := range fields {
+		f = strings.Trim(f, "-")
+		if len(f) < 2 || stopwords[f] {
+			continue
+		}
+		if len(f) > 3 && strings.HasSuffix(f, "s") && !strings.HasSuffix(f, "ss") {
+			f = f[:len(f)-1]
+		}
+		tokens = append(tokens, f)
+	}
+	return tokens
+}
+
+// Index ranks a fixed set of texts by similarity to a query. Words are weighted by
+// how rare they are across the set, so "fix" counts for less than "kubernetes".
+type Index struct {
+	vectors []map[string]float64
+	idf     map[string]float64
+}
+
+// Match is one indexed text and its cosine similarity to the query, from 0 to 1
+type Match struct {
+	Index int
+	Score float64
+}
+
+// NewIndex indexes texts; matches refer to them by position
+func NewIndex(texts []string) *Index {
+	docs := make([][]string, len(texts))
+	df := make(map[string]int)
+	for i, text := range texts {
+		docs[i] = Tokens(text)
+		seen := make(map[string]bool)
+		for _, t := range docs[i] {
+			if !seen[t] {
+				seen[t] = true
+				df[t]++
+			}
+		}
+	}
+
+	ix := &Index{idf: make(map[string]float64, len(df))}
+	for t, n := range df {
+		ix.idf[t] = math.Log(1 + float64(len(texts))/float64(n))
+	}
+	ix.vectors = make([]map[string]float64, len(docs))
+	for i, doc := range docs {
+		ix.vectors[i] = ix.vector(doc)
+	}
+	return ix
+}
+
+// vector returns the normalized tf-idf weights of tokens; unknown words get the
+// weight of a word seen once
+func (ix *Index) vector(tokens []string) map[string]float64 {
+	v := make(map[string]float64)
+	for _, t := range tokens {
+		idf, ok := ix.idf[t]
+		if !ok {
+			idf = math.Log(1 + float64(len(ix.vectors)+1))
+		}
+		v[t] += idf
+	}
+	var norm float64
+	for _, w := range v {
+		norm += w * w
+	}
+	norm = math.Sqrt(norm)
+	for t := range v {
+		v[t] /= norm
+	}
+	return v
+}
+
+// Search returns the indexed texts scoring at least minScore against query, best first
+func (ix *Index) Search(query string, minScore float64) []Match {
+	q := ix.vector(Tokens(query))
+	if len(q) == 0 {
+		return nil
+	}
+
+	var matches []Match
+	for i, v := range ix.vectors {
+		var score float64
+		for t, w := range q {
+			score += w * v[t]
+		}
+		if score > 0 && score >= minScore {
+			matches = append(matches, Match{Index: i, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// Tickets finds issue tracker keys such as JIRA-123 in text
+type Tickets struct {
+	re *regexp.Regexp
+}
+
+// NewTickets matches keys made of one of the prefixes, like "JIRA-", and a number
+func NewTickets(prefixes []string) *Tickets {
+	var alternatives []string
+	for _, p := range prefixes {
+		if p != "" {
+			alternatives = append(alternatives, regexp.QuoteMeta(p))
+		}
+	}
+	if len(alternatives) == 0 {
+		return &Tickets{}
+	}
+	return &Tickets{re: regexp.MustCompile(`(?i)\b(?:` + strings.Join(alternatives, "|") + `)\d+\b`)}
+}
+
+// Keys returns the ticket keys in s, uppercased and without repeats
+func (t *Tickets) Keys(s string) []string {
+	if t.re == nil {
+		return nil
+	}
+	var keys []string
+	for _, k := range t.re.FindAllString(s, -1) {
+		k = strings.ToUpper(k)
+		if !slices.Contains(keys, k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
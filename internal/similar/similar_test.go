@@ -0,0 +1,101 @@
+package similar
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"Update the READMEs", []string{"update", "readme"}},
+		{"update readme", []string{"update", "readme"}},
+		{"Fix tests and docs", []string{"fix", "test", "doc"}},
+		{"Renew the class pass", []string{"renew", "class", "pass"}},
+		{"Is this bus ours", []string{"bus", "our"}},
+		{"a to-do for my team", []string{"to-do", "team"}},
+		{"Fix JIRA-123 login bug", []string{"fix", "jira-123", "login", "bug"}},
+		{"-- dashes --only-- trimmed-", []string{"dashe", "only", "trimmed"}},
+		{"Call Bob, re: v2 API!", []string{"call", "bob", "re", "v2", "api"}},
+		{"x y z", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := Tokens(tt.in); !slices.Equal(got, tt.want) {
+				t.Errorf("Tokens(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ix := NewIndex([]string{
+		"Fix the login bug",                     // 0
+		"Fix kubernetes ingress",                // 1
+		"Write kubernetes ingress docs",         // 2
+		"Buy milk",                              // 3
+		"Fix the login bugs on the signup page", // 4
+	})
+
+	tests := []struct {
+		name     string
+		query    string
+		minScore float64
+		want     []int
+	}{
+		{"best first", "fix login bug", 0, []int{0, 4, 1}},
+		{"rare words count more", "kubernetes fix", 0, []int{1, 2, 0, 4}},
+		{"min score", "fix login bug", 0.5, []int{0, 4}},
+		{"plural matches singular", "fix the login bugs", 0.99, []int{0}},
+		{"no shared words", "call mom", 0, nil},
+		{"only stopwords", "the and of", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := ix.Search(tt.query, tt.minScore)
+			var got []int
+			for i, m := range matches {
+				got = append(got, m.Index)
+				if m.Score < tt.minScore || m.Score > 1+1e-9 {
+					t.Errorf("match %d score = %v, want between %v and 1", m.Index, m.Score, tt.minScore)
+				}
+				if i > 0 && m.Score > matches[i-1].Score {
+					t.Errorf("match %d scores %v, above the one before it", m.Index, m.Score)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTicketsKeys(t *testing.T) {
+	tickets := NewTickets([]string{"JIRA-", "GH-", ""})
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"Fix JIRA-123 login bug", []string{"JIRA-123"}},
+		{"jira-123 and Jira-123 again", []string{"JIRA-123"}},
+		{"GH-7 blocks JIRA-42", []string{"GH-7", "JIRA-42"}},
+		{"(JIRA-9), JIRA-10.", []string{"JIRA-9", "JIRA-10"}},
+		{"XJIRA-1 and JIRA-1x", nil},
+		{"JIRA- without a number", nil},
+		{"OTHER-5", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := tickets.Keys(tt.in); !slices.Equal(got, tt.want) {
+				t.Errorf("Keys(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	if got := NewTickets(nil).Keys("JIRA-123"); got != nil {
+		t.Errorf("Keys without prefixes = %q, want none", got)
+	}
+}
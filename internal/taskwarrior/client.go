@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Task represents a Taskwarrior task
@@ -21,22 +23,110 @@ type Task struct {
 	Status      string   `json:"status,omitempty"`
 	Urgency     float64  `json:"urgency,omitempty"`
 	// Custom UDAs
-	Effort   string `json:"effort,omitempty"`   // E (easy), N (normal), D (difficult)
-	Impact   string `json:"impact,omitempty"`   // H (high), M (medium), L (low)
-	Estimate string `json:"est,omitempty"`      // 15m, 30m, 1h, 2h, 4h, 8h, 2d
-	Fun      string `json:"fun,omitempty"`      // H (high), M (medium), L (low)
-	Blocks   int    `json:"blocks,omitempty"`   // Number of things/people this task unblocks
+	Effort   string `json:"effort,omitempty"` // E (easy), N (normal), D (difficult)
+	Impact   string `json:"impact,omitempty"` // H (high), M (medium), L (low)
+	Estimate string `json:"est,omitempty"`    // 15m, 30m, 1h, 2h, 4h, 8h, 2d
+	Fun      string `json:"fun,omitempty"`    // H (high), M (medium), L (low)
+	Blocks   int    `json:"blocks,omitempty"` // Number of things/people this task unblocks
+
+	Annotations []Annotation      `json:"annotations,omitempty"`
+	UDAs        map[string]string `json:"-"` // other UDAs, e.g. bugwarrior's jiraurl or githubbody
+	Depends     []string          `json:"-"` // UUIDs of tasks to finish first; only set by Add, as export formats differ between versions
 }
 
-// Client interacts with the task command
-type Client struct{}
+// Annotation is a note attached to a task
+type Annotation struct {
+	Entry       string `json:"entry,omitempty"`
+	Description string `json:"description"`
+}
+
+// coreFields are the attributes of task export that aren't UDAs
+var coreFields = map[string]bool{
+	"id": true, "uuid": true, "description": true, "project": true, "priority": true,
+	"due": true, "scheduled": true, "tags": true, "status": true, "urgency": true,
+	"effort": true, "impact": true, "est": true, "fun": true, "blocks": true,
+	"annotations": true, "entry": true, "modified": true, "start": true, "end": true,
+	"wait": true, "until": true, "depends": true, "parent": true, "recur": true,
+	"mask": true, "imask": true, "rtype": true,
+}
+
+// UnmarshalJSON decodes an exported task, collecting the attributes Task has no
+// field for in UDAs
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type plain Task
+	if err := json.Unmarshal(data, (*plain)(t)); err != nil {
+		return err
+	}
+
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return err
+	}
+	for name, raw := range attrs {
+		if coreFields[name] {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			continue
+		}
+		var text string
+		switch v := value.(type) {
+		case string:
+			text = v
+		case float64:
+			text = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		if text == "" {
+			continue
+		}
+		if t.UDAs == nil {
+			t.UDAs = make(map[string]string)
+		}
+		t.UDAs[name] = text
+	}
+	return nil
+}
+
+// exportDateLayout is how task export formats dates (always UTC)
+const exportDateLayout = "20060102T150405Z"
+
+// FormatDate renders an exported date like 20250902T220000Z as a local date, with the
+// time only when it isn't midnight. Anything else is returned unchanged.
+func FormatDate(s string) string {
+	t, err := time.Parse(exportDateLayout, s)
+	if err != nil {
+		return s
+	}
+	t = t.Local()
+	if t.Hour() == 0 && t.Minute() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// Client interacts with the task command. Adds, modifications and annotations are
+// recorded in the undo journal so `tg undo` can revert them.
+type Client struct {
+	journal *Journal
+}
 
 func New() *Client {
-	return &Client{}
+	c := &Client{}
+	if path, err := DefaultJournalPath(); err == nil {
+		c.journal = OpenJournal(path)
+	}
+	return c
 }
 
-// Add creates a new task and returns its UUID
+// Add creates a new task and returns its UUID. Due and scheduled are resolved to
+// absolute dates first; an expression that doesn't resolve fails with a *DateError.
 func (c *Client) Add(t *Task) (string, error) {
+	t, err := c.resolveDates(t)
+	if err != nil {
+		return "", err
+	}
+
 	args := []string{"add"}
 
 	// Add description
@@ -84,6 +174,10 @@ func (c *Client) Add(t *Task) (string, error) {
 		args = append(args, "+"+tag)
 	}
 
+	if len(t.Depends) > 0 {
+		args = append(args, "depends:"+strings.Join(t.Depends, ","))
+	}
+
 	cmd := exec.Command("task", args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -95,7 +189,42 @@ func (c *Client) Add(t *Task) (string, error) {
 
 	// Extract UUID from output (task outputs "Created task <id>." and we need to get UUID)
 	// Run task export to get the UUID of the most recent task
-	return c.getLastTaskUUID()
+	uuid, err := c.getLastTaskUUID()
+	if err != nil {
+		return "", err
+	}
+
+	if c.journal != nil {
+		if err := c.journal.Append(JournalEntry{Op: OpAdd, UUID: uuid, Args: args}); err != nil {
+			return uuid, fmt.Errorf("task added but not journaled: %w", err)
+		}
+	}
+
+	return uuid, nil
+}
+
+// Annotate attaches notes to a task, one annotation each. Each is journaled, so undo
+// removes notes merged into an existing duplicate as well as those on added tasks.
+func (c *Client) Annotate(uuid string, annotations ...string) error {
+	for _, text := range annotations {
+		// -- keeps words like +tag or project:x in the text from being parsed
+		args := []string{uuid, "annotate", "--", text}
+		cmd := exec.Command("task", args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("task annotate failed: %w\nstderr: %s", err, stderr.String())
+		}
+
+		// Like Add, only a note that was made is journaled, so undo never denotates one that wasn't
+		if c.journal != nil {
+			if err := c.journal.Append(JournalEntry{Op: OpAnnotate, UUID: uuid, Args: args}); err != nil {
+				return fmt.Errorf("task annotated but not journaled: %w", err)
+			}
+		}
+	}
+	return nil
 }
 
 func (c *Client) getLastTaskUUID() (string, error) {
@@ -144,8 +273,55 @@ func (c *Client) Export(filter string) ([]Task, error) {
 	return tasks, nil
 }
 
-// Modify updates an existing task (does NOT modify description - preserves bugwarrior sync)
+// Get returns a single task by UUID
+func (c *Client) Get(uuid string) (*Task, error) {
+	tasks, err := c.Export(uuid)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("task %s not found", uuid)
+	}
+	return &tasks[0], nil
+}
+
+// Modify updates an existing task (does NOT modify description - preserves bugwarrior sync).
+// Dates are resolved as in Add.
 func (c *Client) Modify(uuid string, t *Task) error {
+	args, err := c.ModifyArgs(uuid, t)
+	if err != nil {
+		return err
+	}
+
+	// Journal the current state first: a change that can't be undone isn't made
+	if c.journal != nil {
+		before, err := c.Get(uuid)
+		if err != nil {
+			return fmt.Errorf("failed to snapshot task before modify: %w", err)
+		}
+		if err := c.journal.Append(JournalEntry{Op: OpModify, UUID: uuid, Args: args, Before: before}); err != nil {
+			return err
+		}
+	}
+
+	cmd := exec.Command("task", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("task modify failed: %w\nstderr: %s", err, stderr.String())
+	}
+
+	return nil
+}
+
+// ModifyArgs returns the arguments Modify passes to task, e.g. for printing a dry-run
+// plan. Dates are resolved as in Modify, so a plan fails on the same *DateError.
+func (c *Client) ModifyArgs(uuid string, t *Task) ([]string, error) {
+	t, err := c.resolveDates(t)
+	if err != nil {
+		return nil, err
+	}
 	args := []string{uuid, "modify"}
 
 	// NOTE: Description is intentionally NOT updated here
@@ -189,15 +365,44 @@ func (c *Client) Modify(uuid string, t *Task) error {
 		args = append(args, "+"+tag)
 	}
 
-	cmd := exec.Command("task", args...)
-	var stderr bytes.Buffer
+	return args, nil
+}
+
+// CommandLine renders task arguments as a shell command, quoting where needed
+func CommandLine(args []string) string {
+	parts := []string{"task"}
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t'\"\\$`!*?;&|<>()") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// UDAValues returns the allowed values of every UDA declared in .taskrc (uda.<name>.values),
+// including priority which is a UDA since Taskwarrior 2.6
+func (c *Client) UDAValues() (map[string][]string, error) {
+	cmd := exec.Command("task", "_show")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("task modify failed: %w\nstderr: %s", err, stderr.String())
+		return nil, fmt.Errorf("task _show failed: %w\nstderr: %s", err, stderr.String())
 	}
 
-	return nil
+	values := make(map[string][]string)
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || !strings.HasPrefix(key, "uda.") || !strings.HasSuffix(key, ".values") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "uda."), ".values")
+		values[name] = strings.Split(value, ",")
+	}
+
+	return values, nil
 }
 
 // GetUntaggedTasks returns tasks without beacon tags (for batch enrichment)
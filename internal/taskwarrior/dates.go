@@ -0,0 +1,252 @@
+package taskwarrior
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DateError reports a due or scheduled value that isn't a date Taskwarrior understands
+type DateError struct {
+	Field string // "due" or "scheduled"
+	Expr  string
+}
+
+func (e *DateError) Error() string {
+	return fmt.Sprintf("%s: %q is not a valid date", e.Field, e.Expr)
+}
+
+// dateArgLayout is the ISO form tg submits resolved dates in
+const dateArgLayout = "2006-01-02T15:04:05"
+
+// DateArg formats a resolved date for a due: or scheduled: argument
+func DateArg(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(dateArgLayout)
+}
+
+var weekStart = sync.OnceValue(func() time.Weekday {
+	out, err := exec.Command("task", "rc.verbose=nothing", "_get", "rc.weekstart").Output()
+	if err == nil && strings.EqualFold(strings.TrimSpace(string(out)), "monday") {
+		return time.Monday
+	}
+	return time.Sunday
+})
+
+// WeekStart returns the first day of the week per rc.weekstart: Sunday, Taskwarrior's
+// default, unless it is set to Monday. It is read from task once.
+func WeekStart() time.Weekday {
+	return weekStart()
+}
+
+// weekSynonyms depend on rc.weekstart, so ResolveDate leaves them to Taskwarrior
+var weekSynonyms = []string{"sow", "socw", "eow", "eocw", "soww", "eoww"}
+
+// ResolveDate turns a date expression like "friday", "eom" or "2025-03-01" into an
+// absolute time. Common Taskwarrior synonyms are resolved locally; week synonyms and
+// anything else are handed to `task calc`, so they agree with what task would store.
+func (c *Client) ResolveDate(expr string) (time.Time, error) {
+	weekly := slices.Contains(weekSynonyms, strings.ToLower(strings.TrimSpace(expr)))
+	if !weekly {
+		if t, ok := ParseDate(expr, time.Now(), WeekStart()); ok {
+			return t, nil
+		}
+	}
+
+	t, err := calcDate(expr)
+	if err != nil && weekly {
+		// Without task calc, fall back to rc.weekstart as read from task
+		if t, ok := ParseDate(expr, time.Now(), WeekStart()); ok {
+			return t, nil
+		}
+	}
+	return t, err
+}
+
+func calcDate(expr string) (time.Time, error) {
+	cmd := exec.Command("task", "rc.verbose=nothing", "calc", expr)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return time.Time{}, &DateError{Expr: expr}
+	}
+
+	// calc echoes expressions it can't evaluate as a date
+	t, err := time.ParseInLocation(dateArgLayout, strings.TrimSpace(stdout.String()), time.Local)
+	if err != nil {
+		return time.Time{}, &DateError{Expr: expr}
+	}
+	return t, nil
+}
+
+// resolveDates returns a copy of t with due and scheduled replaced by absolute dates,
+// so what tg previewed is exactly what gets stored
+func (c *Client) resolveDates(t *Task) (*Task, error) {
+	resolved := *t
+	for _, f := range []struct {
+		name  string
+		value *string
+	}{{"due", &resolved.Due}, {"scheduled", &resolved.Scheduled}} {
+		if *f.value == "" {
+			continue
+		}
+		date, err := c.ResolveDate(*f.value)
+		if err != nil {
+			return nil, &DateError{Field: f.name, Expr: *f.value}
+		}
+		*f.value = DateArg(date)
+	}
+	return &resolved, nil
+}
+
+// ParseDate resolves the Taskwarrior date synonyms tg knows about relative to now:
+// ISO dates, today/tomorrow/yesterday, sod/eod, weekday and month names, the
+// start/end-of-period names (sow, eom, socy, ...), ordinals like 15th, later/someday
+// and durations like 3d or 2weeks. Weeks (sow, socw, eow, eocw) start on weekStart, as
+// with rc.weekstart; the work week (soww, eoww) runs Monday to Friday within that week.
+func ParseDate(expr string, now time.Time, weekStart time.Weekday) (time.Time, bool) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return time.Time{}, false
+	}
+
+	for _, layout := range []string{"2006-01-02", dateArgLayout, "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, expr, now.Location()); err == nil {
+			return t, true
+		}
+	}
+
+	s := strings.ToLower(expr)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	endOfDay := func(t time.Time) time.Time { return t.Add(24*time.Hour - time.Second) }
+	// First day of the current week
+	week := today.AddDate(0, 0, -(int(today.Weekday())-int(weekStart)+7)%7)
+	// Monday of the current week's work week
+	workWeek := week.AddDate(0, 0, (int(time.Monday)-int(weekStart)+7)%7)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	year := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+
+	switch s {
+	case "now":
+		return now, true
+	case "today", "sod":
+		return today, true
+	case "eod":
+		return endOfDay(today), true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	case "sow":
+		return week.AddDate(0, 0, 7), true
+	case "socw":
+		return week, true
+	case "eow", "eocw":
+		return endOfDay(week.AddDate(0, 0, 6)), true
+	case "soww":
+		return workWeek.AddDate(0, 0, 7), true
+	case "eoww":
+		return endOfDay(workWeek.AddDate(0, 0, 4)), true
+	case "som":
+		return month.AddDate(0, 1, 0), true
+	case "socm":
+		return month, true
+	case "eom", "eocm":
+		return endOfDay(month.AddDate(0, 1, -1)), true
+	case "soy":
+		return year.AddDate(1, 0, 0), true
+	case "socy":
+		return year, true
+	case "eoy", "eocy":
+		return endOfDay(year.AddDate(1, 0, -1)), true
+	case "later", "someday":
+		return time.Date(9999, 12, 30, 0, 0, 0, 0, now.Location()), true
+	}
+
+	// Weekday names mean the next such day, a week ahead if it is today
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			days := (int(d) - int(today.Weekday()) + 7) % 7
+			if days == 0 {
+				days = 7
+			}
+			return today.AddDate(0, 0, days), true
+		}
+	}
+
+	// Month names mean the first of the next such month
+	for m := time.January; m <= time.December; m++ {
+		name := strings.ToLower(m.String())
+		if s == name || s == name[:3] {
+			t := time.Date(now.Year(), m, 1, 0, 0, 0, 0, now.Location())
+			if !t.After(today) {
+				t = t.AddDate(1, 0, 0)
+			}
+			return t, true
+		}
+	}
+
+	if t, ok := parseOrdinal(s, today); ok {
+		return t, true
+	}
+	return parseOffset(s, now)
+}
+
+// parseOrdinal resolves "1st" to "31st" to the next day of the month with that number
+func parseOrdinal(s string, today time.Time) (time.Time, bool) {
+	if len(s) < 3 {
+		return time.Time{}, false
+	}
+	suffix := s[len(s)-2:]
+	if suffix != "st" && suffix != "nd" && suffix != "rd" && suffix != "th" {
+		return time.Time{}, false
+	}
+	day, err := strconv.Atoi(s[:len(s)-2])
+	if err != nil || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+
+	for i := 0; i < 12; i++ {
+		t := time.Date(today.Year(), today.Month()+time.Month(i), day, 0, 0, 0, 0, today.Location())
+		// Skip months that don't have the day (time.Date would roll over)
+		if t.Day() == day && t.After(today) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseOffset resolves a duration such as "3d", "2 weeks" or "1mo" to now plus that duration
+func parseOffset(s string, now time.Time) (time.Time, bool) {
+	s = strings.ReplaceAll(s, " ", "")
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	n, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	switch strings.TrimSuffix(s[i:], "s") {
+	case "h", "hr", "hour":
+		return now.Add(time.Duration(n) * time.Hour), true
+	case "d", "day":
+		return now.AddDate(0, 0, n), true
+	case "w", "wk", "week":
+		return now.AddDate(0, 0, 7*n), true
+	case "mo", "month":
+		return now.AddDate(0, n, 0), true
+	case "y", "yr", "year":
+		return now.AddDate(n, 0, 0), true
+	}
+	return time.Time{}, false
+}
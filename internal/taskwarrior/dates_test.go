@@ -0,0 +1,103 @@
+package taskwarrior
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	// Wednesday
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	day := func(month time.Month, d int) time.Time { return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC) }
+	endOf := func(month time.Month, d int) time.Time { return time.Date(2025, month, d, 23, 59, 59, 0, time.UTC) }
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"2025-03-01", day(time.March, 1)},
+		{"2025-03-01T14:00", time.Date(2025, 3, 1, 14, 0, 0, 0, time.UTC)},
+		{"2025-03-01 14:00", time.Date(2025, 3, 1, 14, 0, 0, 0, time.UTC)},
+		{"now", now},
+		{"today", day(time.January, 15)},
+		{"sod", day(time.January, 15)},
+		{"eod", endOf(time.January, 15)},
+		{"Tomorrow", day(time.January, 16)},
+		{"yesterday", day(time.January, 14)},
+		{"friday", day(time.January, 17)},
+		{"mon", day(time.January, 20)},
+		{"wednesday", day(time.January, 22)},
+		{"march", day(time.March, 1)},
+		{"jan", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"som", day(time.February, 1)},
+		{"socm", day(time.January, 1)},
+		{"eom", endOf(time.January, 31)},
+		{"soy", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"socy", day(time.January, 1)},
+		{"eoy", endOf(time.December, 31)},
+		{"20th", day(time.January, 20)},
+		{"15th", day(time.February, 15)},
+		{"3d", time.Date(2025, 1, 18, 10, 30, 0, 0, time.UTC)},
+		{"2 weeks", time.Date(2025, 1, 29, 10, 30, 0, 0, time.UTC)},
+		{"1mo", time.Date(2025, 2, 15, 10, 30, 0, 0, time.UTC)},
+		{"4h", time.Date(2025, 1, 15, 14, 30, 0, 0, time.UTC)},
+		{"someday", time.Date(9999, 12, 30, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, ok := ParseDate(tt.expr, now, time.Sunday)
+			if !ok || !got.Equal(tt.want) {
+				t.Errorf("ParseDate(%q) = %v, %v, want %v", tt.expr, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDateWeekStart(t *testing.T) {
+	wednesday := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	sunday := time.Date(2025, 1, 19, 10, 30, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+	endOf := func(d int) time.Time { return time.Date(2025, 1, d, 23, 59, 59, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		expr      string
+		now       time.Time
+		weekStart time.Weekday
+		want      time.Time
+	}{
+		{"sow sunday start", "sow", wednesday, time.Sunday, day(19)},
+		{"sow monday start", "sow", wednesday, time.Monday, day(20)},
+		{"socw sunday start", "socw", wednesday, time.Sunday, day(12)},
+		{"socw monday start", "socw", wednesday, time.Monday, day(13)},
+		{"eow sunday start", "eow", wednesday, time.Sunday, endOf(18)},
+		{"eow monday start", "eow", wednesday, time.Monday, endOf(19)},
+		{"eocw sunday start", "eocw", wednesday, time.Sunday, endOf(18)},
+		{"soww sunday start", "soww", wednesday, time.Sunday, day(20)},
+		{"soww monday start", "soww", wednesday, time.Monday, day(20)},
+		{"eoww sunday start", "eoww", wednesday, time.Sunday, endOf(17)},
+		{"eoww monday start", "eoww", wednesday, time.Monday, endOf(17)},
+		{"sow on a sunday, sunday start", "sow", sunday, time.Sunday, day(26)},
+		{"sow on a sunday, monday start", "sow", sunday, time.Monday, day(20)},
+		{"socw on a sunday, sunday start", "socw", sunday, time.Sunday, day(19)},
+		{"eoww on a sunday, sunday start", "eoww", sunday, time.Sunday, endOf(24)},
+		{"eoww on a sunday, monday start", "eoww", sunday, time.Monday, endOf(17)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseDate(tt.expr, tt.now, tt.weekStart)
+			if !ok || !got.Equal(tt.want) {
+				t.Errorf("ParseDate(%q) = %v, %v, want %v", tt.expr, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDateRejects(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	for _, expr := range []string{"", "  ", "soon", "next friday", "2025-13-01", "32nd", "3 fortnights"} {
+		if got, ok := ParseDate(expr, now, time.Sunday); ok {
+			t.Errorf("ParseDate(%q) = %v, want no match", expr, got)
+		}
+	}
+}
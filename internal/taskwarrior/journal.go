@@ -0,0 +1,363 @@
+package taskwarrior
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bf/tg/internal/config"
+)
+
+// Journal operations
+const (
+	OpAdd      = "add"
+	OpModify   = "modify"
+	OpAnnotate = "annotate"
+	OpUndo     = "undo"      // marks Session as undone
+	OpUndoStep = "undo-step" // marks one change of Session as reverted by an undo that didn't finish
+)
+
+// Session identifies the tg process that made a change; every Client in the
+// process records under the same session
+var Session = newSessionID()
+
+func newSessionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// JournalEntry is one change tg made through the Client
+type JournalEntry struct {
+	Time    time.Time `json:"time"`
+	Session string    `json:"session"`
+	Op      string    `json:"op"`
+	UUID    string    `json:"uuid,omitempty"`
+	Args    []string  `json:"args,omitempty"`
+	Before  *Task     `json:"before,omitempty"` // snapshot taken before a modify
+	// Reverted is the time of the change an undo-step entry marks as reverted
+	Reverted time.Time `json:"reverted,omitzero"`
+}
+
+// Journal is an append-only JSONL log of tg-initiated changes, used by Undo
+type Journal struct {
+	path string
+}
+
+// SessionSummary describes the changes one session made
+type SessionSummary struct {
+	ID        string
+	Started   time.Time
+	Added     int
+	Modified  int
+	Annotated int
+	Undone    bool
+}
+
+func OpenJournal(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// DefaultJournalPath returns journal.jsonl in tg's state directory
+func DefaultJournalPath() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "journal.jsonl"), nil
+}
+
+// Append writes an entry, stamping it with the current time and session if unset
+func (j *Journal) Append(e JournalEntry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Session == "" {
+		e.Session = Session
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create journal dir: %w", err)
+	}
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// Entries reads the whole journal in the order it was written
+func (j *Journal) Entries() ([]JournalEntry, error) {
+	f, err := os.Open(j.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue // skip a torn line rather than lose the whole journal
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	return entries, nil
+}
+
+// Sessions summarizes the journal per session, oldest first
+func (j *Journal) Sessions() ([]SessionSummary, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []SessionSummary
+	index := make(map[string]int)
+	for _, e := range entries {
+		if e.Op == OpUndo {
+			if i, ok := index[e.Session]; ok {
+				sessions[i].Undone = true
+			}
+			continue
+		}
+		if e.Op == OpUndoStep {
+			continue
+		}
+		i, ok := index[e.Session]
+		if !ok {
+			i = len(sessions)
+			index[e.Session] = i
+			sessions = append(sessions, SessionSummary{ID: e.Session, Started: e.Time})
+		}
+		switch e.Op {
+		case OpAdd:
+			sessions[i].Added++
+		case OpModify:
+			sessions[i].Modified++
+		case OpAnnotate:
+			sessions[i].Annotated++
+		}
+	}
+	return sessions, nil
+}
+
+// UndoReport lists what Undo reverted
+type UndoReport struct {
+	Session   string
+	Deleted   []string // tasks tg had created
+	Restored  []string // tasks tg had modified
+	Denotated []string // tasks tg had annotated without creating them, e.g. by a merge
+	Errors    []error
+}
+
+// Undo reverts every change a session made, newest first: tasks it created are
+// deleted, fields it modified get their journaled values back and annotations it
+// attached to other tasks are removed. Fields the session didn't touch are left
+// alone, so later manual edits survive. An empty session means the most recent one
+// that hasn't been undone yet. When a change can't be reverted the session stays
+// open, and undoing it again retries only the changes still in place.
+func (c *Client) Undo(session string) (*UndoReport, error) {
+	if c.journal == nil {
+		return nil, fmt.Errorf("no undo journal available")
+	}
+
+	if session == "" {
+		sessions, err := c.journal.Sessions()
+		if err != nil {
+			return nil, err
+		}
+		for i := len(sessions) - 1; i >= 0; i-- {
+			if !sessions[i].Undone {
+				session = sessions[i].ID
+				break
+			}
+		}
+		if session == "" {
+			return nil, fmt.Errorf("nothing to undo")
+		}
+	}
+
+	entries, err := c.journal.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	// Annotations on tasks the session created go away with the tasks
+	added := make(map[string]bool)
+	for _, e := range entries {
+		if e.Session == session && e.Op == OpAdd {
+			added[e.UUID] = true
+		}
+	}
+
+	// Changes an earlier, partly failed undo already reverted are skipped on retry
+	reverted := make(map[string]bool)
+	for _, e := range entries {
+		if e.Session == session && e.Op == OpUndoStep {
+			reverted[stepKey(e.UUID, e.Reverted)] = true
+		}
+	}
+
+	report := &UndoReport{Session: session}
+	found := false
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Session != session {
+			continue
+		}
+		switch e.Op {
+		case OpUndo:
+			return nil, fmt.Errorf("session %s was already undone", session)
+		case OpAdd, OpModify, OpAnnotate:
+			found = true
+		default:
+			continue
+		}
+		if reverted[stepKey(e.UUID, e.Time)] {
+			continue
+		}
+
+		var err error
+		switch e.Op {
+		case OpAdd:
+			if err = runUnjournaled("rc.confirmation=off", e.UUID, "delete"); err != nil {
+				err = fmt.Errorf("delete %s: %w", e.UUID, err)
+				break
+			}
+			report.Deleted = append(report.Deleted, e.UUID)
+		case OpModify:
+			if e.Before == nil {
+				continue
+			}
+			if err = runUnjournaled(restoreArgs(e)...); err != nil {
+				err = fmt.Errorf("restore %s: %w", e.UUID, err)
+				break
+			}
+			report.Restored = append(report.Restored, e.UUID)
+		case OpAnnotate:
+			if added[e.UUID] || len(e.Args) == 0 {
+				continue
+			}
+			text := e.Args[len(e.Args)-1]
+			if err = runUnjournaled(e.UUID, "denotate", "--", text); err != nil {
+				err = fmt.Errorf("denotate %s: %w", e.UUID, err)
+				break
+			}
+			report.Denotated = append(report.Denotated, e.UUID)
+		}
+		if err == nil {
+			err = c.journal.Append(JournalEntry{Op: OpUndoStep, Session: session, UUID: e.UUID, Reverted: e.Time})
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, err)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("session %s not found in journal", session)
+	}
+
+	// A session is only undone once every change is reverted, so a failed one can be retried
+	if len(report.Errors) > 0 {
+		return report, nil
+	}
+	if err := c.journal.Append(JournalEntry{Op: OpUndo, Session: session}); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// stepKey identifies a journaled change by its task and when it was made
+func stepKey(uuid string, t time.Time) string {
+	return uuid + "@" + t.UTC().Format(time.RFC3339Nano)
+}
+
+// restoreArgs builds the modify that puts back the fields a journaled modify changed:
+// attributes get their previous value (empty clears them), added tags are removed
+func restoreArgs(e JournalEntry) []string {
+	args := []string{e.UUID, "modify"}
+	for _, arg := range e.Args {
+		if arg == e.UUID || arg == "modify" {
+			continue
+		}
+		if tag, ok := strings.CutPrefix(arg, "+"); ok {
+			if !slices.Contains(e.Before.Tags, tag) {
+				args = append(args, "-"+tag)
+			}
+			continue
+		}
+		if key, _, ok := strings.Cut(arg, ":"); ok {
+			args = append(args, key+":"+fieldValue(e.Before, key))
+		}
+	}
+	return args
+}
+
+// fieldValue returns a task attribute in the form task modify accepts
+func fieldValue(t *Task, key string) string {
+	switch key {
+	case "project":
+		return t.Project
+	case "priority":
+		return t.Priority
+	case "due":
+		return t.Due
+	case "scheduled":
+		return t.Scheduled
+	case "effort":
+		return t.Effort
+	case "impact":
+		return t.Impact
+	case "est":
+		return t.Estimate
+	case "fun":
+		return t.Fun
+	case "blocks":
+		if t.Blocks == 0 {
+			return ""
+		}
+		return strconv.Itoa(t.Blocks)
+	}
+	return ""
+}
+
+// runUnjournaled runs task directly, bypassing the journal (undo must not record itself)
+func runUnjournaled(args ...string) error {
+	cmd := exec.Command("task", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
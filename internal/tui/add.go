@@ -4,15 +4,15 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
-	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 
 	"github.com/bf/tg/internal/config"
 	"github.com/bf/tg/internal/llm"
 	"github.com/bf/tg/internal/taskwarrior"
+	"github.com/bf/tg/internal/usage"
 )
 
 type state int
@@ -22,24 +22,45 @@ const (
 	statePreview
 	stateEditing
 	stateConfirm
+	stateSplitting
+	stateSplitPreview
+	stateDuplicates
 	stateDone
 	stateError
 )
 
 type AddModel struct {
-	cfg         *config.Config
-	provider    llm.Provider
-	twClient    *taskwarrior.Client
-	original    string
-	enrichment  *llm.Enrichment
-	state       state
-	spinner     spinner.Model
-	err         error
-	editField   int
-	textInputs  []textinput.Model
-	fieldNames  []string
-	result      string
-	skipEnrich  bool
+	cfg        *config.Config
+	provider   llm.Provider
+	twClient   *taskwarrior.Client
+	original   string
+	enrichment *llm.Enrichment
+	suggested  *llm.Enrichment // the enrichment as the model suggested it, for the corrections log
+	fixes      []llm.Fix
+	selection  *selection
+	dates      map[string]dateResult // resolved due/scheduled expressions
+	notice     string                // shown in edit mode or the preview, e.g. why the add was refused
+	progress   llm.Progress
+	progressCh chan llm.Progress
+	partial    *llm.Enrichment // the reply streamed so far, shown while loading
+	partialCh  chan *llm.Enrichment
+	state      state
+	spinner    spinner.Model
+	err        error
+	editField  int
+	editors    []fieldEditor
+	fieldNames []string
+	result     string
+	skipEnrich bool
+	split      *llm.Split // the subtasks suggested by [b] or --split
+	splitFixes []llm.Fix
+	withParent bool // add a tracking task depending on all subtasks
+	splitFirst bool // --split: no enrichment of the task as a whole
+	added      int  // tasks added from a split
+
+	duplicates      []duplicate // pending tasks the new one may repeat
+	duplicateCursor int
+	merged          *taskwarrior.Task // the task merged into instead of adding
 }
 
 type enrichmentMsg struct {
@@ -47,6 +68,10 @@ type enrichmentMsg struct {
 	err        error
 }
 
+type progressMsg llm.Progress
+
+type partialMsg *llm.Enrichment
+
 type taskAddedMsg struct {
 	uuid string
 	err  error
@@ -57,18 +82,8 @@ func NewAddModel(cfg *config.Config, provider llm.Provider, description string)
 	s.Spinner = spinner.Dot
 	s.Style = spinnerStyle
 
-	// Create text inputs for editing
+	// Edit mode form: free text, pickers and tag checklists
 	fields := []string{"Description", "Beacons", "Directions", "Project", "Priority", "Due", "Scheduled", "Effort", "Impact", "Estimate", "Fun", "Blocks"}
-	inputs := make([]textinput.Model, len(fields))
-	for i := range inputs {
-		ti := textinput.New()
-		ti.Prompt = ""
-		ti.CharLimit = 256
-		if i == 0 {
-			ti.Focus()
-		}
-		inputs[i] = ti
-	}
 
 	return &AddModel{
 		cfg:        cfg,
@@ -77,12 +92,16 @@ func NewAddModel(cfg *config.Config, provider llm.Provider, description string)
 		original:   description,
 		state:      stateLoading,
 		spinner:    s,
-		textInputs: inputs,
+		editors:    newFieldEditors(cfg, fields),
 		fieldNames: fields,
+		dates:      make(map[string]dateResult),
 	}
 }
 
 func (m *AddModel) Init() tea.Cmd {
+	if m.splitFirst {
+		return tea.Batch(m.spinner.Tick, m.fetchSplit())
+	}
 	return tea.Batch(
 		m.spinner.Tick,
 		m.fetchEnrichment(),
@@ -90,15 +109,84 @@ func (m *AddModel) Init() tea.Cmd {
 }
 
 func (m *AddModel) fetchEnrichment() tea.Cmd {
-	return func() tea.Msg {
-		enrichment, err := m.provider.Enrich(
-			context.Background(),
-			m.original,
-			m.cfg.Beacons,
-			m.cfg.Projects,
-		)
+	progress := make(chan llm.Progress, 1)
+	partials := make(chan *llm.Enrichment, 1)
+	m.progressCh = progress
+	m.partialCh = partials
+
+	ctx := llm.WithProgress(context.Background(), relayProgress(progress))
+	if !m.cfg.LLM.NoStream {
+		ctx = llm.WithPartial(ctx, relayPartial(partials))
+	}
+	fetch := func() tea.Msg {
+		defer close(progress)
+		defer close(partials)
+		enrichment, err := m.provider.Enrich(ctx, taskwarrior.Task{Description: m.original}, m.cfg.Beacons, m.cfg.Projects)
 		return enrichmentMsg{enrichment: enrichment, err: err}
 	}
+	return tea.Batch(fetch, listenProgress(progress), listenPartial(partials))
+}
+
+// relayProgress forwards provider progress reports to ch, replacing a report
+// the TUI hasn't picked up yet so the provider never blocks
+func relayProgress(ch chan llm.Progress) func(llm.Progress) {
+	return func(p llm.Progress) {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- p
+	}
+}
+
+// listenProgress waits for the next progress report; it stops once ch is closed
+func listenProgress(ch <-chan llm.Progress) tea.Cmd {
+	return func() tea.Msg {
+		p, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return progressMsg(p)
+	}
+}
+
+// relayPartial forwards the streamed reply to ch like relayProgress; only the
+// latest one matters
+func relayPartial(ch chan *llm.Enrichment) func(*llm.Enrichment) {
+	return func(e *llm.Enrichment) {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- e
+	}
+}
+
+// listenPartial waits for more of the streamed reply; it stops once ch is closed
+func listenPartial(ch <-chan *llm.Enrichment) tea.Cmd {
+	return func() tea.Msg {
+		e, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return partialMsg(e)
+	}
+}
+
+// formatAttempt shows the retry count next to the spinner once the first attempt failed,
+// and the provider a fallback chain moved on to
+func formatAttempt(p llm.Progress) string {
+	var notes []string
+	if p.Fallback != "" {
+		notes = append(notes, "falling back to "+p.Fallback)
+	}
+	if p.Attempt > 1 {
+		notes = append(notes, fmt.Sprintf("attempt %d/%d", p.Attempt, p.MaxAttempts))
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return " " + warningStyle.Render("("+strings.Join(notes, ", ")+")")
 }
 
 func (m *AddModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -107,12 +195,24 @@ func (m *AddModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleKeyMsg(msg)
 
 	case spinner.TickMsg:
-		if m.state == stateLoading {
+		if m.state == stateLoading || m.state == stateSplitting {
 			var cmd tea.Cmd
 			m.spinner, cmd = m.spinner.Update(msg)
 			return m, cmd
 		}
 
+	case progressMsg:
+		// A retry or fallback streams its reply from scratch
+		m.progress = llm.Progress(msg)
+		m.partial = nil
+		return m, listenProgress(m.progressCh)
+
+	case partialMsg:
+		if m.state == stateLoading {
+			m.partial = msg
+		}
+		return m, listenPartial(m.partialCh)
+
 	case enrichmentMsg:
 		if msg.err != nil {
 			m.err = msg.err
@@ -120,11 +220,24 @@ func (m *AddModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		m.enrichment = msg.enrichment
-		m.populateInputs()
+		m.fixes = llm.Validate(m.enrichment, m.cfg)
+		suggested := *m.enrichment
+		m.suggested = &suggested
+		m.selection = newSelection(m.enrichment, m.cfg)
+		m.selection.addAnnotations(m.enrichment)
 		m.state = statePreview
+		return m, resolveDates(m.twClient, m.dates, m.enrichment)
+
+	case datesResolvedMsg:
+		for expr, r := range msg {
+			m.dates[expr] = r
+		}
 		return m, nil
 
 	case taskAddedMsg:
+		if m.editDate(msg.err) {
+			return m, nil
+		}
 		if msg.err != nil {
 			m.err = msg.err
 			m.state = stateError
@@ -133,13 +246,37 @@ func (m *AddModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.result = msg.uuid
 		m.state = stateDone
 		return m, tea.Quit
+
+	case splitMsg:
+		return m.handleSplit(msg)
+
+	case duplicatesMsg:
+		return m.handleDuplicates(msg)
+
+	case mergedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			m.state = stateError
+			return m, nil
+		}
+		m.merged = &msg.task
+		m.state = stateDone
+		return m, tea.Quit
+
+	case splitAddedMsg:
+		m.added = msg.added
+		if msg.err != nil {
+			m.err = msg.err
+			m.state = stateError
+			return m, nil
+		}
+		m.state = stateDone
+		return m, tea.Quit
 	}
 
-	// Update text inputs if editing
+	// Update the focused editor if editing
 	if m.state == stateEditing {
-		var cmd tea.Cmd
-		m.textInputs[m.editField], cmd = m.textInputs[m.editField].Update(msg)
-		return m, cmd
+		return m, m.editors[m.editField].Update(msg)
 	}
 
 	return m, nil
@@ -147,11 +284,17 @@ func (m *AddModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m *AddModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch m.state {
-	case stateLoading:
+	case stateLoading, stateSplitting:
 		if msg.String() == "ctrl+c" || msg.String() == "esc" {
 			return m, tea.Quit
 		}
 
+	case stateSplitPreview:
+		return m.handleSplitKey(msg)
+
+	case stateDuplicates:
+		return m.handleDuplicateKey(msg)
+
 	case statePreview:
 		switch msg.String() {
 		case "ctrl+c", "q":
@@ -159,18 +302,28 @@ func (m *AddModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		case "esc":
 			return m, tea.Quit
 		case "enter", "a":
-			// Accept and add task
-			return m, m.addTask()
+			// Accept and add task, unless a selected date is known to be invalid
+			if m.editDate(invalidDate(m.selection.apply(m.enrichment), m.dates)) {
+				return m, nil
+			}
+			return m, m.checkDuplicates()
 		case "e":
-			// Enter edit mode
-			m.state = stateEditing
-			m.editField = 0
-			m.textInputs[0].Focus()
+			// Enter edit mode with only the selected values
+			m.startEditing(0)
 			return m, nil
+		case "b":
+			// Break down into subtasks; the selected values become the tracking task
+			if m.editDate(invalidDate(m.selection.apply(m.enrichment), m.dates)) {
+				return m, nil
+			}
+			return m, m.startSplit()
 		case "s":
 			// Skip enrichment, add original
 			m.skipEnrich = true
-			return m, m.addTask()
+			return m, m.checkDuplicates()
+		default:
+			m.selection.handleKey(msg.String())
+			return m, nil
 		}
 
 	case stateEditing:
@@ -179,28 +332,27 @@ func (m *AddModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		case "esc":
 			// Exit edit mode
-			m.state = statePreview
-			return m, nil
+			return m, m.stopEditing()
 		case "enter":
 			// Save and go to next field or exit
 			m.updateEnrichmentFromInputs()
-			if m.editField < len(m.textInputs)-1 {
-				m.textInputs[m.editField].Blur()
+			if m.editField < len(m.editors)-1 {
+				m.editors[m.editField].Blur()
 				m.editField++
-				m.textInputs[m.editField].Focus()
+				m.editors[m.editField].Focus()
 			} else {
-				m.state = statePreview
+				return m, m.stopEditing()
 			}
 			return m, nil
 		case "tab":
-			m.textInputs[m.editField].Blur()
-			m.editField = (m.editField + 1) % len(m.textInputs)
-			m.textInputs[m.editField].Focus()
+			m.editors[m.editField].Blur()
+			m.editField = (m.editField + 1) % len(m.editors)
+			m.editors[m.editField].Focus()
 			return m, nil
 		case "shift+tab":
-			m.textInputs[m.editField].Blur()
-			m.editField = (m.editField - 1 + len(m.textInputs)) % len(m.textInputs)
-			m.textInputs[m.editField].Focus()
+			m.editors[m.editField].Blur()
+			m.editField = (m.editField - 1 + len(m.editors)) % len(m.editors)
+			m.editors[m.editField].Focus()
 			return m, nil
 		}
 
@@ -212,48 +364,78 @@ func (m *AddModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	// Update current text input
 	if m.state == stateEditing {
-		var cmd tea.Cmd
-		m.textInputs[m.editField], cmd = m.textInputs[m.editField].Update(msg)
-		return m, cmd
+		return m, m.editors[m.editField].Update(msg)
 	}
 
 	return m, nil
 }
 
+// startEditing enters edit mode on the given field with only the selected values
+func (m *AddModel) startEditing(field int) {
+	m.enrichment = m.selection.apply(m.enrichment)
+	m.populateInputs()
+	m.state = stateEditing
+	m.editors[m.editField].Blur()
+	m.editField = field
+	m.editors[field].Focus()
+}
+
+// stopEditing returns to the preview and resolves any edited dates
+func (m *AddModel) stopEditing() tea.Cmd {
+	m.notice = ""
+	prev := m.selection
+	m.selection = newSelection(m.enrichment, m.cfg)
+	m.selection.addAnnotations(m.enrichment)
+	m.selection.keepAnnotations(prev)
+	m.state = statePreview
+	return resolveDates(m.twClient, m.dates, m.enrichment)
+}
+
+// editDate sends the user to the date field err complains about, if it is a date error
+func (m *AddModel) editDate(err error) bool {
+	field, ok := dateField(err, m.fieldNames)
+	if !ok {
+		return false
+	}
+	m.startEditing(field)
+	m.notice = err.Error()
+	return true
+}
+
 func (m *AddModel) populateInputs() {
 	if m.enrichment == nil {
 		return
 	}
-	m.textInputs[0].SetValue(m.enrichment.Description)
-	m.textInputs[1].SetValue(strings.Join(m.enrichment.Beacons, " "))
-	m.textInputs[2].SetValue(strings.Join(m.enrichment.Directions, " "))
-	m.textInputs[3].SetValue(m.enrichment.Project)
-	m.textInputs[4].SetValue(m.enrichment.Priority)
-	m.textInputs[5].SetValue(m.enrichment.Due)
-	m.textInputs[6].SetValue(m.enrichment.Scheduled)
-	m.textInputs[7].SetValue(m.enrichment.Effort)
-	m.textInputs[8].SetValue(m.enrichment.Impact)
-	m.textInputs[9].SetValue(m.enrichment.Estimate)
-	m.textInputs[10].SetValue(m.enrichment.Fun)
-	m.textInputs[11].SetValue(fmt.Sprintf("%d", m.enrichment.Blocks))
+	m.editors[0].SetValue(m.enrichment.Description)
+	m.editors[1].SetValue(strings.Join(m.enrichment.Beacons, " "))
+	m.editors[2].SetValue(strings.Join(m.enrichment.Directions, " "))
+	m.editors[3].SetValue(m.enrichment.Project)
+	m.editors[4].SetValue(m.enrichment.Priority)
+	m.editors[5].SetValue(m.enrichment.Due)
+	m.editors[6].SetValue(m.enrichment.Scheduled)
+	m.editors[7].SetValue(m.enrichment.Effort)
+	m.editors[8].SetValue(m.enrichment.Impact)
+	m.editors[9].SetValue(m.enrichment.Estimate)
+	m.editors[10].SetValue(m.enrichment.Fun)
+	m.editors[11].SetValue(blocksValue(m.enrichment.Blocks))
 }
 
 func (m *AddModel) updateEnrichmentFromInputs() {
 	if m.enrichment == nil {
 		m.enrichment = &llm.Enrichment{}
 	}
-	m.enrichment.Description = m.textInputs[0].Value()
-	m.enrichment.Beacons = splitTags(m.textInputs[1].Value())
-	m.enrichment.Directions = splitTags(m.textInputs[2].Value())
-	m.enrichment.Project = m.textInputs[3].Value()
-	m.enrichment.Priority = m.textInputs[4].Value()
-	m.enrichment.Due = m.textInputs[5].Value()
-	m.enrichment.Scheduled = m.textInputs[6].Value()
-	m.enrichment.Effort = m.textInputs[7].Value()
-	m.enrichment.Impact = m.textInputs[8].Value()
-	m.enrichment.Estimate = m.textInputs[9].Value()
-	m.enrichment.Fun = m.textInputs[10].Value()
-	m.enrichment.Blocks = parseBlocks(m.textInputs[11].Value())
+	m.enrichment.Description = m.editors[0].Value()
+	m.enrichment.Beacons = splitTags(m.editors[1].Value())
+	m.enrichment.Directions = splitTags(m.editors[2].Value())
+	m.enrichment.Project = m.editors[3].Value()
+	m.enrichment.Priority = m.editors[4].Value()
+	m.enrichment.Due = m.editors[5].Value()
+	m.enrichment.Scheduled = m.editors[6].Value()
+	m.enrichment.Effort = m.editors[7].Value()
+	m.enrichment.Impact = m.editors[8].Value()
+	m.enrichment.Estimate = m.editors[9].Value()
+	m.enrichment.Fun = m.editors[10].Value()
+	m.enrichment.Blocks = parseBlocks(m.editors[11].Value())
 }
 
 func splitTags(s string) []string {
@@ -282,37 +464,58 @@ func parseBlocks(s string) int {
 }
 
 func (m *AddModel) addTask() tea.Cmd {
+	// Only the values left selected in the preview are added
+	var e *llm.Enrichment
+	var annotations []string
+	if !m.skipEnrich {
+		e = m.selection.apply(m.enrichment)
+		annotations = m.selection.annotations()
+	}
+
 	return func() tea.Msg {
-		var task taskwarrior.Task
-
-		if m.skipEnrich {
-			task.Description = m.original
-		} else {
-			task.Description = m.enrichment.Description
-			task.Project = m.enrichment.Project
-			task.Priority = m.enrichment.Priority
-			task.Due = m.enrichment.Due
-			task.Scheduled = m.enrichment.Scheduled
-			task.Effort = m.enrichment.Effort
-			task.Impact = m.enrichment.Impact
-			task.Estimate = m.enrichment.Estimate
-			task.Fun = m.enrichment.Fun
-			task.Blocks = m.enrichment.Blocks
-
-			// Combine beacons and directions as tags
-			task.Tags = append(task.Tags, m.enrichment.Beacons...)
-			task.Tags = append(task.Tags, m.enrichment.Directions...)
-
-			if m.enrichment.IsWaste {
-				task.Tags = append(task.Tags, "waste")
-			}
+		task := taskwarrior.Task{Description: m.original}
+		if !m.skipEnrich {
+			task = enrichedTask(e)
 		}
 
 		uuid, err := m.twClient.Add(&task)
+		if err == nil && !m.skipEnrich {
+			recordCorrection(m.original, m.suggested, e)
+		}
+		if err == nil {
+			if err = m.twClient.Annotate(uuid, annotations...); err != nil {
+				err = fmt.Errorf("task %s added, but annotating it failed: %w", uuid, err)
+			}
+		}
 		return taskAddedMsg{uuid: uuid, err: err}
 	}
 }
 
+// enrichedTask is the task an enrichment describes
+func enrichedTask(e *llm.Enrichment) taskwarrior.Task {
+	task := taskwarrior.Task{
+		Description: e.Description,
+		Project:     e.Project,
+		Priority:    e.Priority,
+		Due:         e.Due,
+		Scheduled:   e.Scheduled,
+		Effort:      e.Effort,
+		Impact:      e.Impact,
+		Estimate:    e.Estimate,
+		Fun:         e.Fun,
+		Blocks:      e.Blocks,
+	}
+
+	// Combine beacons and directions as tags
+	task.Tags = append(task.Tags, e.Beacons...)
+	task.Tags = append(task.Tags, e.Directions...)
+
+	if e.IsWaste {
+		task.Tags = append(task.Tags, "waste")
+	}
+	return task
+}
+
 func (m *AddModel) View() string {
 	switch m.state {
 	case stateLoading:
@@ -321,6 +524,12 @@ func (m *AddModel) View() string {
 		return m.viewPreview()
 	case stateEditing:
 		return m.viewEditing()
+	case stateSplitting:
+		return m.viewSplitting()
+	case stateSplitPreview:
+		return m.viewSplitPreview()
+	case stateDuplicates:
+		return m.viewDuplicates()
 	case stateDone:
 		return m.viewDone()
 	case stateError:
@@ -331,18 +540,61 @@ func (m *AddModel) View() string {
 }
 
 func (m *AddModel) viewLoading() string {
-	return fmt.Sprintf("\n  %s Analyzing task with LLM...\n\n  %s\n",
+	s := fmt.Sprintf("\n  %s Analyzing task with LLM...%s\n\n  %s\n",
 		m.spinner.View(),
+		formatAttempt(m.progress),
 		subtitleStyle.Render(m.original),
 	)
+	if m.partial != nil {
+		s += "\n" + formatPartial(m.partial)
+	}
+	return s
+}
+
+// formatPartial lists the fields of a streaming reply that have arrived so far,
+// in the order the preview shows them, with the reasoning as it is written
+func formatPartial(e *llm.Enrichment) string {
+	var sb strings.Builder
+	field := func(label, value string) {
+		if value != "" {
+			sb.WriteString("  " + labelStyle.Render(label+":") + " " + valueStyle.Render(value) + "\n")
+		}
+	}
+	field("Description", e.Description)
+	field("Beacons", strings.Join(e.Beacons, " "))
+	field("Directions", strings.Join(e.Directions, " "))
+	field("Project", e.Project)
+	field("Priority", e.Priority)
+	field("Due", e.Due)
+	field("Scheduled", e.Scheduled)
+	field("Effort", e.Effort)
+	field("Impact", e.Impact)
+	field("Estimate", e.Estimate)
+	field("Fun", e.Fun)
+	if e.Blocks > 0 {
+		field("Blocks", fmt.Sprint(e.Blocks))
+	}
+	if e.Reasoning != "" {
+		sb.WriteString("\n  " + labelStyle.Render("Reasoning:") + "\n")
+		sb.WriteString(subtitleStyle.PaddingLeft(2).Width(78).Render(e.Reasoning) + "\n")
+	}
+	return sb.String()
 }
 
 func (m *AddModel) viewPreview() string {
 	var sb strings.Builder
 
 	sb.WriteString(titleStyle.Render("tg add") + "\n\n")
+	if m.notice != "" {
+		sb.WriteString(warningStyle.Render("! "+m.notice) + "\n\n")
+	}
 	sb.WriteString(labelStyle.Render("Original:") + " " + subtitleStyle.Render(m.original) + "\n\n")
 
+	if m.enrichment.Cached {
+		sb.WriteString(cachedTagStyle.Render("CACHED") + " " + subtitleStyle.Render("Served from the local cache, no LLM call") + "\n\n")
+	}
+	sb.WriteString(formatProvider(m.enrichment))
+
 	if m.enrichment.IsWaste {
 		sb.WriteString(wasteTagStyle.Render(" WASTE ") + " " + subtitleStyle.Render("This task doesn't align with any beacon") + "\n\n")
 	}
@@ -352,44 +604,15 @@ func (m *AddModel) viewPreview() string {
 
 	content.WriteString(labelStyle.Render("Description:") + " " + valueStyle.Render(m.enrichment.Description) + "\n")
 
-	// Beacons
-	content.WriteString(labelStyle.Render("Beacons:") + " ")
-	if len(m.enrichment.Beacons) > 0 {
-		tags := make([]string, len(m.enrichment.Beacons))
-		for i, b := range m.enrichment.Beacons {
-			tags[i] = tagStyle.Render(b)
-		}
-		content.WriteString(strings.Join(tags, " "))
-	} else {
-		content.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render("none"))
-	}
-	content.WriteString("\n")
-
-	// Directions
-	content.WriteString(labelStyle.Render("Directions:") + " ")
-	if len(m.enrichment.Directions) > 0 {
-		tags := make([]string, len(m.enrichment.Directions))
-		for i, d := range m.enrichment.Directions {
-			tags[i] = directionTagStyle.Render(d)
-		}
-		content.WriteString(strings.Join(tags, " "))
-	} else {
-		content.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render("none"))
-	}
-	content.WriteString("\n")
+	// Suggested values, each of which can be accepted or rejected
+	content.WriteString("\n" + m.selection.view(m.dates) + "\n\n")
+	content.WriteString(labelStyle.Render("Confidence:") + " " + formatConfidence(m.enrichment.Confidence) + "\n")
+	content.WriteString(formatUsage(m.enrichment.Usage))
 
-	// Project, Priority, Dates
-	content.WriteString(labelStyle.Render("Project:") + " " + valueOrNone(m.enrichment.Project) + "\n")
-	content.WriteString(labelStyle.Render("Priority:") + " " + valueOrNone(m.enrichment.Priority) + "\n")
-	content.WriteString(labelStyle.Render("Due:") + " " + valueOrNone(m.enrichment.Due) + " " + lipgloss.NewStyle().Foreground(mutedColor).Render("(hard deadline)") + "\n")
-	content.WriteString(labelStyle.Render("Scheduled:") + " " + valueOrNone(m.enrichment.Scheduled) + " " + lipgloss.NewStyle().Foreground(mutedColor).Render("(soft due date)") + "\n")
-
-	// UDAs: Effort, Impact, Estimate, Fun, Blocks
-	content.WriteString(labelStyle.Render("Effort:") + " " + formatUDA(m.enrichment.Effort, "E=Easy N=Normal D=Difficult") + "\n")
-	content.WriteString(labelStyle.Render("Impact:") + " " + formatUDA(m.enrichment.Impact, "H=High M=Medium L=Low") + "\n")
-	content.WriteString(labelStyle.Render("Estimate:") + " " + valueOrNone(m.enrichment.Estimate) + "\n")
-	content.WriteString(labelStyle.Render("Fun:") + " " + formatUDA(m.enrichment.Fun, "H=Fun M=Neutral L=Boring") + "\n")
-	content.WriteString(labelStyle.Render("Blocks:") + " " + formatBlocks(m.enrichment.Blocks) + "\n")
+	// Values the validator repaired or flagged
+	if len(m.fixes) > 0 {
+		content.WriteString("\n" + formatFixes(m.fixes))
+	}
 
 	// Reasoning
 	if m.enrichment.Reasoning != "" {
@@ -398,7 +621,7 @@ func (m *AddModel) viewPreview() string {
 
 	sb.WriteString(boxStyle.Render(content.String()))
 	sb.WriteString("\n\n")
-	sb.WriteString(helpStyle.Render("[enter/a] Accept  [e] Edit  [s] Skip LLM  [esc/q] Cancel"))
+	sb.WriteString(helpStyle.Render("[↑/↓] Move  [space] Toggle  [←/→] Change  [enter/a] Accept selected  [e] Edit  [b] Break down  [s] Skip LLM  [esc/q] Cancel"))
 
 	return sb.String()
 }
@@ -407,6 +630,9 @@ func (m *AddModel) viewEditing() string {
 	var sb strings.Builder
 
 	sb.WriteString(titleStyle.Render("tg add - Edit Mode") + "\n\n")
+	if m.notice != "" {
+		sb.WriteString(warningStyle.Render("! "+m.notice) + "\n\n")
+	}
 
 	for i, name := range m.fieldNames {
 		style := labelStyle
@@ -414,17 +640,23 @@ func (m *AddModel) viewEditing() string {
 			style = selectedStyle
 		}
 		sb.WriteString(style.Render(name+":") + " ")
-		sb.WriteString(m.textInputs[i].View())
+		sb.WriteString(m.editors[i].View(i == m.editField))
 		sb.WriteString("\n")
 	}
 
 	sb.WriteString("\n")
-	sb.WriteString(helpStyle.Render("[tab] Next field  [shift+tab] Previous  [enter] Save  [esc] Cancel edit"))
+	sb.WriteString(helpStyle.Render("[tab] Next field  [shift+tab] Previous  [←/→] Pick value  [space] Check tag  [enter] Save  [esc] Cancel edit"))
 
 	return sb.String()
 }
 
 func (m *AddModel) viewDone() string {
+	if m.merged != nil {
+		return successStyle.Render("Merged into existing task: "+m.merged.Description) + "\n"
+	}
+	if m.added > 0 {
+		return successStyle.Render(fmt.Sprintf("Added %d tasks!", m.added)) + "\n"
+	}
 	return successStyle.Render("Task added successfully!") + "\n"
 }
 
@@ -433,32 +665,60 @@ func (m *AddModel) viewError() string {
 		helpStyle.Render("Press any key to exit")
 }
 
-func valueOrNone(s string) string {
-	if s == "" {
-		return lipgloss.NewStyle().Foreground(mutedColor).Render("--")
+func formatConfidence(c float64) string {
+	text := fmt.Sprintf("%.0f%%", c*100)
+	if c < 0.5 {
+		return warningStyle.Render(text + " (low)")
+	}
+	return valueStyle.Render(text)
+}
+
+// formatProvider names the fallback chain provider that produced e and why the ones
+// before it failed; it is empty without a chain
+func formatProvider(e *llm.Enrichment) string {
+	if e.Provider == "" {
+		return ""
 	}
-	return valueStyle.Render(s)
+	s := labelStyle.Render("Provider:") + " " + valueStyle.Render(e.Provider) + "\n"
+	for _, f := range e.Failed {
+		s += warningStyle.Render("  ⚠ "+truncateText(strings.ReplaceAll(f, "\n", " "), 100)) + "\n"
+	}
+	return s + "\n"
 }
 
-func formatUDA(value, hint string) string {
-	if value == "" {
-		return lipgloss.NewStyle().Foreground(mutedColor).Render("--")
+// formatUsage shows the tokens, latency and estimated cost of the LLM calls behind an
+// enrichment; it is empty when none were made
+func formatUsage(u llm.Usage) string {
+	if u.Calls == 0 {
+		return ""
 	}
-	return valueStyle.Render(value) + " " + lipgloss.NewStyle().Foreground(mutedColor).Render("("+hint+")")
+	text := fmt.Sprintf("%d in / %d out tokens, %s, %s", u.InputTokens, u.OutputTokens,
+		u.Latency.Round(100*time.Millisecond), usage.FormatCost(u.Cost))
+	if u.Calls > 1 {
+		text += fmt.Sprintf(" over %d calls", u.Calls)
+	}
+	return labelStyle.Render("Usage:") + " " + subtitleStyle.Render(text) + "\n"
 }
 
-func formatBlocks(n int) string {
-	if n == 0 {
-		return lipgloss.NewStyle().Foreground(mutedColor).Render("0 (not blocking)")
+func formatFixes(fixes []llm.Fix) string {
+	var sb strings.Builder
+	sb.WriteString(labelStyle.Render("Fixes:") + "\n")
+	for _, f := range fixes {
+		sb.WriteString("  " + warningStyle.Render("! "+f.String()) + "\n")
 	}
-	var hint string
+	return sb.String()
+}
+
+// blocksHint describes how much a task blocks others
+func blocksHint(n int) string {
 	switch {
 	case n >= 6:
-		hint = "critical blocker"
+		return "critical blocker"
 	case n >= 3:
-		hint = "significant blocker"
+		return "significant blocker"
+	case n > 0:
+		return "minor blocker"
 	default:
-		hint = "minor blocker"
+		return "not blocking"
 	}
-	return valueStyle.Render(fmt.Sprintf("%d", n)) + " " + lipgloss.NewStyle().Foreground(mutedColor).Render("("+hint+")")
 }
@@ -0,0 +1,500 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/llm"
+	"github.com/bf/tg/internal/taskwarrior"
+)
+
+type batchState int
+
+const (
+	batchStateLoading batchState = iota
+	batchStateReview
+	batchStateEditing
+	batchStateAdding
+	batchStateDone
+	batchStateError
+)
+
+// batchRow is one item of the list under review
+type batchRow struct {
+	original   string
+	enrichment *llm.Enrichment
+	suggested  *llm.Enrichment // the enrichment as the model suggested it, for the corrections log
+	fixes      []llm.Fix
+	dropped    bool
+	uuid       string // set once the task is added
+}
+
+// BatchModel enriches a list of new tasks at once, for tg add --file, and reviews
+// them in a table before adding the ones kept
+type BatchModel struct {
+	cfg        *config.Config
+	provider   llm.Provider
+	twClient   *taskwarrior.Client
+	rows       []batchRow
+	cursor     int
+	offset     int // first row shown, as the table scrolls
+	height     int // terminal height, 0 until the first resize
+	state      batchState
+	spinner    spinner.Model
+	progress   llm.Progress
+	progressCh chan llm.Progress
+	usage      llm.Usage
+	notice     string // shown in the review and edit mode, e.g. why an add was refused
+	err        error
+	added      int
+	// Edit mode, on the row under the cursor
+	editField  int
+	editors    []fieldEditor
+	fieldNames []string
+}
+
+type batchEnrichedMsg struct {
+	enrichments []*llm.Enrichment
+	err         error
+}
+
+type batchAddedMsg struct {
+	row  int
+	uuid string
+	err  error
+}
+
+func NewBatchModel(cfg *config.Config, provider llm.Provider, descriptions []string) *BatchModel {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = spinnerStyle
+
+	fields := []string{"Description", "Beacons", "Directions", "Project", "Priority", "Due", "Scheduled", "Effort", "Impact", "Estimate", "Fun", "Blocks"}
+
+	rows := make([]batchRow, len(descriptions))
+	for i, d := range descriptions {
+		rows[i].original = d
+	}
+
+	return &BatchModel{
+		cfg:        cfg,
+		provider:   provider,
+		twClient:   taskwarrior.New(),
+		rows:       rows,
+		state:      batchStateLoading,
+		spinner:    s,
+		editors:    newFieldEditors(cfg, fields),
+		fieldNames: fields,
+	}
+}
+
+func (m *BatchModel) Init() tea.Cmd {
+	return tea.Batch(m.spinner.Tick, m.fetchEnrichments())
+}
+
+func (m *BatchModel) fetchEnrichments() tea.Cmd {
+	progress := make(chan llm.Progress, 1)
+	m.progressCh = progress
+
+	tasks := make([]taskwarrior.Task, len(m.rows))
+	for i, row := range m.rows {
+		tasks[i].Description = row.original
+	}
+	fetch := func() tea.Msg {
+		defer close(progress)
+		enrichments, err := m.provider.EnrichBatch(
+			llm.WithProgress(context.Background(), relayProgress(progress)),
+			tasks,
+			m.cfg.Beacons,
+			m.cfg.Projects,
+		)
+		return batchEnrichedMsg{enrichments: enrichments, err: err}
+	}
+	return tea.Batch(fetch, listenProgress(progress))
+}
+
+func (m *BatchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		return m.handleKeyMsg(msg)
+
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		m.scroll()
+		return m, nil
+
+	case spinner.TickMsg:
+		if m.state == batchStateLoading || m.state == batchStateAdding {
+			var cmd tea.Cmd
+			m.spinner, cmd = m.spinner.Update(msg)
+			return m, cmd
+		}
+
+	case progressMsg:
+		m.progress = llm.Progress(msg)
+		return m, listenProgress(m.progressCh)
+
+	case batchEnrichedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			m.state = batchStateError
+			return m, nil
+		}
+		for i, e := range msg.enrichments {
+			row := &m.rows[i]
+			row.enrichment = e
+			row.fixes = llm.Validate(e, m.cfg)
+			suggested := *e
+			row.suggested = &suggested
+			m.usage.Add(e.Usage)
+		}
+		m.state = batchStateReview
+		return m, nil
+
+	case batchAddedMsg:
+		row := &m.rows[msg.row]
+		if msg.uuid != "" {
+			row.uuid = msg.uuid
+			m.added++
+		}
+		if msg.err != nil {
+			m.state = batchStateReview
+			m.cursor = msg.row
+			m.scroll()
+			if !m.editDate(msg.err) {
+				m.notice = fmt.Sprintf("Row %d: %v", msg.row+1, msg.err)
+			}
+			return m, nil
+		}
+		return m, m.addNext()
+	}
+
+	if m.state == batchStateEditing {
+		return m, m.editors[m.editField].Update(msg)
+	}
+	return m, nil
+}
+
+func (m *BatchModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch m.state {
+	case batchStateLoading, batchStateAdding:
+		if msg.String() == "ctrl+c" || msg.String() == "esc" {
+			return m, tea.Quit
+		}
+
+	case batchStateReview:
+		switch msg.String() {
+		case "ctrl+c", "q", "esc":
+			return m, tea.Quit
+		case "up", "k":
+			m.cursor = max(m.cursor-1, 0)
+		case "down", "j":
+			m.cursor = min(m.cursor+1, len(m.rows)-1)
+		case "pgup":
+			m.cursor = max(m.cursor-m.visibleRows(), 0)
+		case "pgdown":
+			m.cursor = min(m.cursor+m.visibleRows(), len(m.rows)-1)
+		case "home", "g":
+			m.cursor = 0
+		case "end", "G":
+			m.cursor = len(m.rows) - 1
+		case " ", "d":
+			// Added rows are in taskwarrior already
+			if row := &m.rows[m.cursor]; row.uuid == "" {
+				row.dropped = !row.dropped
+			}
+		case "e":
+			if m.rows[m.cursor].uuid == "" {
+				m.startEditing(0)
+			}
+		case "enter", "a":
+			m.notice = ""
+			m.state = batchStateAdding
+			return m, tea.Batch(m.spinner.Tick, m.addNext())
+		}
+		m.scroll()
+		return m, nil
+
+	case batchStateEditing:
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			m.stopEditing()
+			return m, nil
+		case "enter":
+			m.updateEnrichmentFromInputs()
+			if m.editField < len(m.editors)-1 {
+				m.editors[m.editField].Blur()
+				m.editField++
+				m.editors[m.editField].Focus()
+			} else {
+				m.stopEditing()
+			}
+			return m, nil
+		case "tab":
+			m.editors[m.editField].Blur()
+			m.editField = (m.editField + 1) % len(m.editors)
+			m.editors[m.editField].Focus()
+			return m, nil
+		case "shift+tab":
+			m.editors[m.editField].Blur()
+			m.editField = (m.editField - 1 + len(m.editors)) % len(m.editors)
+			m.editors[m.editField].Focus()
+			return m, nil
+		}
+		return m, m.editors[m.editField].Update(msg)
+
+	case batchStateError:
+		if msg.String() == "ctrl+c" || msg.String() == "esc" || msg.String() == "q" || msg.String() == "enter" {
+			return m, tea.Quit
+		}
+	}
+
+	return m, nil
+}
+
+// addNext adds the next row that is kept and not added yet, one at a time so a
+// failure stops the run at that row; it quits once every kept row is added
+func (m *BatchModel) addNext() tea.Cmd {
+	for i, row := range m.rows {
+		if row.dropped || row.uuid != "" {
+			continue
+		}
+		e := row.enrichment
+		var annotations []string
+		for _, a := range e.Annotations() {
+			annotations = append(annotations, a.Text)
+		}
+		return func() tea.Msg {
+			task := enrichedTask(e)
+			uuid, err := m.twClient.Add(&task)
+			if err == nil {
+				recordCorrection(row.original, row.suggested, e)
+				if err = m.twClient.Annotate(uuid, annotations...); err != nil {
+					err = fmt.Errorf("task %s added, but annotating it failed: %w", uuid, err)
+				}
+			}
+			return batchAddedMsg{row: i, uuid: uuid, err: err}
+		}
+	}
+	m.state = batchStateDone
+	return tea.Quit
+}
+
+func (m *BatchModel) startEditing(field int) {
+	e := m.rows[m.cursor].enrichment
+	m.editors[0].SetValue(e.Description)
+	m.editors[1].SetValue(strings.Join(e.Beacons, " "))
+	m.editors[2].SetValue(strings.Join(e.Directions, " "))
+	m.editors[3].SetValue(e.Project)
+	m.editors[4].SetValue(e.Priority)
+	m.editors[5].SetValue(e.Due)
+	m.editors[6].SetValue(e.Scheduled)
+	m.editors[7].SetValue(e.Effort)
+	m.editors[8].SetValue(e.Impact)
+	m.editors[9].SetValue(e.Estimate)
+	m.editors[10].SetValue(e.Fun)
+	m.editors[11].SetValue(blocksValue(e.Blocks))
+
+	m.state = batchStateEditing
+	m.editors[m.editField].Blur()
+	m.editField = field
+	m.editors[field].Focus()
+}
+
+func (m *BatchModel) stopEditing() {
+	m.notice = ""
+	m.state = batchStateReview
+}
+
+// editDate sends the user to the date field err complains about, if it is a date error
+func (m *BatchModel) editDate(err error) bool {
+	field, ok := dateField(err, m.fieldNames)
+	if !ok {
+		return false
+	}
+	m.startEditing(field)
+	m.notice = fmt.Sprintf("Row %d: %v", m.cursor+1, err)
+	return true
+}
+
+func (m *BatchModel) updateEnrichmentFromInputs() {
+	e := m.rows[m.cursor].enrichment
+	e.Description = m.editors[0].Value()
+	e.Beacons = splitTags(m.editors[1].Value())
+	e.Directions = splitTags(m.editors[2].Value())
+	e.Project = m.editors[3].Value()
+	e.Priority = m.editors[4].Value()
+	e.Due = m.editors[5].Value()
+	e.Scheduled = m.editors[6].Value()
+	e.Effort = m.editors[7].Value()
+	e.Impact = m.editors[8].Value()
+	e.Estimate = m.editors[9].Value()
+	e.Fun = m.editors[10].Value()
+	e.Blocks = parseBlocks(m.editors[11].Value())
+}
+
+// visibleRows is how many table rows fit next to the details of the current row
+func (m *BatchModel) visibleRows() int {
+	if m.height == 0 {
+		return 15
+	}
+	if m.rows[m.cursor].enrichment == nil {
+		return max(m.height-10, 3)
+	}
+	return max(m.height-lipgloss.Height(m.viewDetails())-10, 3)
+}
+
+// scroll keeps the cursor within the rows shown
+func (m *BatchModel) scroll() {
+	n := m.visibleRows()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+n {
+		m.offset = m.cursor - n + 1
+	}
+	m.offset = max(min(m.offset, len(m.rows)-n), 0)
+}
+
+func (m *BatchModel) View() string {
+	switch m.state {
+	case batchStateLoading:
+		return fmt.Sprintf("\n  %s Analyzing %d tasks with LLM...%s\n",
+			m.spinner.View(), len(m.rows), formatAttempt(m.progress))
+	case batchStateReview, batchStateAdding:
+		return m.viewReview()
+	case batchStateEditing:
+		return m.viewEditing()
+	case batchStateDone:
+		return successStyle.Render(fmt.Sprintf("Added %d tasks!", m.added)) + "\n"
+	case batchStateError:
+		return errorStyle.Render("Error: "+m.err.Error()) + "\n\n" +
+			helpStyle.Render("Press any key to exit")
+	default:
+		return ""
+	}
+}
+
+func (m *BatchModel) viewReview() string {
+	var sb strings.Builder
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+
+	kept := 0
+	for _, row := range m.rows {
+		if !row.dropped {
+			kept++
+		}
+	}
+	sb.WriteString(titleStyle.Render(fmt.Sprintf("tg add - %d tasks", len(m.rows))) + "\n\n")
+	if m.notice != "" {
+		sb.WriteString(warningStyle.Render("! "+m.notice) + "\n\n")
+	}
+
+	// Cells are padded before styling, as escape codes would throw the widths off
+	sb.WriteString(muted.Bold(true).Render(fmt.Sprintf("     %3s  %-42s %-12s %-4s %-3s %-10s %s", "#", "Description", "Project", "Est", "Pri", "Due", "Tags")) + "\n")
+	n := m.visibleRows()
+	if m.offset > 0 {
+		sb.WriteString(muted.Render(fmt.Sprintf("     ↑ %d more", m.offset)) + "\n")
+	}
+	for i := m.offset; i < min(m.offset+n, len(m.rows)); i++ {
+		row := m.rows[i]
+		e := row.enrichment
+
+		cursor := "  "
+		if i == m.cursor {
+			cursor = selectedStyle.Render("› ")
+		}
+		mark := successStyle.Render("✓")
+		switch {
+		case row.uuid != "":
+			mark = successStyle.Render("+")
+		case row.dropped:
+			mark = muted.Render("✗")
+		case len(row.fixes) > 0:
+			mark = warningStyle.Render("!")
+		}
+
+		tags := strings.Join(append(append([]string{}, e.Beacons...), e.Directions...), " ")
+		cells := fmt.Sprintf("%3d  %-42s %-12s %-4s %-3s %-10s %s", i+1,
+			truncateText(e.Description, 42), truncateText(e.Project, 12), e.Estimate, e.Priority,
+			truncateText(e.Due, 10), truncateText(tags, 40))
+		switch {
+		case row.dropped:
+			cells = muted.Strikethrough(true).Render(cells)
+		case row.uuid != "":
+			cells = muted.Render(cells)
+		case i == m.cursor:
+			cells = selectedStyle.Render(cells)
+		default:
+			cells = valueStyle.Render(cells)
+		}
+		sb.WriteString(cursor + mark + "  " + cells + "\n")
+	}
+	if rest := len(m.rows) - m.offset - n; rest > 0 {
+		sb.WriteString(muted.Render(fmt.Sprintf("     ↓ %d more", rest)) + "\n")
+	}
+
+	sb.WriteString("\n" + boxStyle.Render(m.viewDetails()) + "\n")
+	summary := fmt.Sprintf("%d of %d kept", kept, len(m.rows))
+	if m.added > 0 {
+		summary += fmt.Sprintf(", %d added", m.added)
+	}
+	sb.WriteString(subtitleStyle.Render(summary) + "\n")
+	sb.WriteString(formatUsage(m.usage) + "\n")
+
+	if m.state == batchStateAdding {
+		sb.WriteString(m.spinner.View() + " Adding tasks...")
+	} else {
+		sb.WriteString(helpStyle.Render("[↑/↓] Move  [space/d] Keep/drop  [e] Edit  [enter/a] Add kept  [esc/q] Cancel"))
+	}
+	return sb.String()
+}
+
+// viewDetails shows the current row in full: what was written, every suggested value
+// and the repairs the validator made
+func (m *BatchModel) viewDetails() string {
+	row := m.rows[m.cursor]
+	var sb strings.Builder
+	sb.WriteString("  " + labelStyle.Render("Original:") + " " + subtitleStyle.Render(row.original))
+	if row.enrichment.Cached {
+		sb.WriteString(" " + cachedTagStyle.Render("CACHED"))
+	}
+	sb.WriteString("\n")
+	sb.WriteString(strings.TrimSuffix(formatPartial(row.enrichment), "\n"))
+	if len(row.fixes) > 0 {
+		sb.WriteString("\n\n" + strings.TrimSuffix(formatFixes(row.fixes), "\n"))
+	}
+	return sb.String()
+}
+
+func (m *BatchModel) viewEditing() string {
+	var sb strings.Builder
+
+	sb.WriteString(titleStyle.Render(fmt.Sprintf("tg add - Edit Row %d", m.cursor+1)) + "\n\n")
+	if m.notice != "" {
+		sb.WriteString(warningStyle.Render("! "+m.notice) + "\n\n")
+	}
+
+	for i, name := range m.fieldNames {
+		style := labelStyle
+		if i == m.editField {
+			style = selectedStyle
+		}
+		sb.WriteString(style.Render(name+":") + " ")
+		sb.WriteString(m.editors[i].View(i == m.editField))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(helpStyle.Render("[tab] Next field  [shift+tab] Previous  [←/→] Pick value  [space] Check tag  [enter] Save  [esc] Back to the list"))
+
+	return sb.String()
+}
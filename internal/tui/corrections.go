@@ -0,0 +1,38 @@
+package tui
+
+import (
+	"github.com/bf/tg/internal/feedback"
+	"github.com/bf/tg/internal/llm"
+)
+
+// recordCorrection logs what the model suggested next to what the user accepted, so
+// tg learn can pick up the corrections they keep making
+func recordCorrection(description string, suggested, final *llm.Enrichment) {
+	if suggested == nil || final == nil {
+		return
+	}
+	path, err := feedback.DefaultLogPath()
+	if err != nil {
+		return
+	}
+	// The log is best effort: failing to write it mustn't fail the add or modify
+	feedback.OpenLog(path).Append(feedback.Correction{
+		Description: description,
+		Suggested:   feedbackValues(suggested),
+		Final:       feedbackValues(final),
+	})
+}
+
+func feedbackValues(e *llm.Enrichment) feedback.Values {
+	return feedback.Values{
+		Beacons:    e.Beacons,
+		Directions: e.Directions,
+		Project:    e.Project,
+		Priority:   e.Priority,
+		Effort:     e.Effort,
+		Impact:     e.Impact,
+		Estimate:   e.Estimate,
+		Fun:        e.Fun,
+		Blocks:     e.Blocks,
+	}
+}
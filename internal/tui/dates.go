@@ -0,0 +1,117 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/bf/tg/internal/llm"
+	"github.com/bf/tg/internal/taskwarrior"
+)
+
+// dateResult is a due or scheduled expression resolved to an absolute date
+type dateResult struct {
+	date time.Time
+	err  error
+}
+
+type datesResolvedMsg map[string]dateResult
+
+// resolveDates resolves the date expressions of e that aren't in known yet
+func resolveDates(client *taskwarrior.Client, known map[string]dateResult, e *llm.Enrichment) tea.Cmd {
+	var exprs []string
+	for _, expr := range []string{e.Due, e.Scheduled} {
+		if _, ok := known[expr]; expr == "" || ok || slices.Contains(exprs, expr) {
+			continue
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 0 {
+		return nil
+	}
+
+	return func() tea.Msg {
+		resolved := make(datesResolvedMsg)
+		for _, expr := range exprs {
+			date, err := client.ResolveDate(expr)
+			resolved[expr] = dateResult{date: date, err: err}
+		}
+		return resolved
+	}
+}
+
+// invalidDate returns the first selected date known not to resolve
+func invalidDate(e *llm.Enrichment, dates map[string]dateResult) error {
+	if r, ok := dates[e.Due]; e.Due != "" && ok && r.err != nil {
+		return &taskwarrior.DateError{Field: "due", Expr: e.Due}
+	}
+	if r, ok := dates[e.Scheduled]; e.Scheduled != "" && ok && r.err != nil {
+		return &taskwarrior.DateError{Field: "scheduled", Expr: e.Scheduled}
+	}
+	return nil
+}
+
+// dateField returns the edit form field to send the user to when err is a date
+// that didn't resolve
+func dateField(err error, fieldNames []string) (int, bool) {
+	var dateErr *taskwarrior.DateError
+	if !errors.As(err, &dateErr) {
+		return 0, false
+	}
+	i := slices.IndexFunc(fieldNames, func(name string) bool {
+		return strings.EqualFold(name, dateErr.Field)
+	})
+	return i, i >= 0
+}
+
+// formatResolved shows the absolute date an expression resolves to
+func formatResolved(expr string, dates map[string]dateResult) string {
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+	r, ok := dates[expr]
+	switch {
+	case !ok:
+		return muted.Render("resolving…")
+	case r.err != nil:
+		return errorStyle.Render("✗ not a valid date")
+	}
+
+	layout := "Mon 2006-01-02"
+	if r.date.Hour() != 0 || r.date.Minute() != 0 {
+		layout += " 15:04"
+	}
+	return muted.Render("→ " + r.date.Format(layout) + ", " + relativeDate(r.date, time.Now()))
+}
+
+// relativeDate describes t in calendar days from now, e.g. "tomorrow" or "in 3 weeks"
+func relativeDate(t, now time.Time) string {
+	day := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC) }
+	days := int(day(t).Sub(day(now)).Hours() / 24)
+
+	unit, n := "day", days
+	switch abs := max(days, -days); {
+	case days == 0:
+		return "today"
+	case days == 1:
+		return "tomorrow"
+	case days == -1:
+		return "yesterday"
+	case abs >= 60:
+		unit, n = "month", days/30
+	case abs >= 14:
+		unit, n = "week", days/7
+	}
+
+	text := fmt.Sprintf("%d %s", max(n, -n), unit)
+	if max(n, -n) != 1 {
+		text += "s"
+	}
+	if days < 0 {
+		return text + " ago"
+	}
+	return "in " + text
+}
@@ -0,0 +1,234 @@
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/similar"
+	"github.com/bf/tg/internal/taskwarrior"
+)
+
+// duplicateScore is the similarity from which a pending task counts as a likely
+// duplicate; short descriptions share a word or two with many tasks below it
+const duplicateScore = 0.6
+
+// maxDuplicates keeps the list to the likeliest matches
+const maxDuplicates = 3
+
+// duplicate is a pending task that may be the one being added
+type duplicate struct {
+	task  taskwarrior.Task
+	score float64
+	key   string // the ticket key both mention, if any
+}
+
+type duplicatesMsg []duplicate
+
+type mergedMsg struct {
+	task taskwarrior.Task
+	err  error
+}
+
+// checkDuplicates looks for pending tasks the new one may duplicate before it is
+// added. The check is best effort: without the pending tasks the add goes ahead.
+func (m *AddModel) checkDuplicates() tea.Cmd {
+	descriptions := []string{m.original}
+	if !m.skipEnrich {
+		descriptions = append(descriptions, m.selection.apply(m.enrichment).Description)
+	}
+
+	return func() tea.Msg {
+		tasks, err := m.twClient.Export("status:pending")
+		if err != nil {
+			return duplicatesMsg(nil)
+		}
+		return duplicatesMsg(findDuplicates(tasks, descriptions, m.cfg.Projects))
+	}
+}
+
+// findDuplicates returns the tasks sharing a ticket key with any of the descriptions,
+// then those similar enough to one of them, best first
+func findDuplicates(tasks []taskwarrior.Task, descriptions []string, projects []config.Project) []duplicate {
+	texts := make([]string, len(tasks))
+	for i, t := range tasks {
+		texts[i] = t.Description
+	}
+	index := similar.NewIndex(texts)
+
+	found := make(map[int]duplicate)
+	for _, desc := range descriptions {
+		for _, match := range index.Search(desc, duplicateScore) {
+			if match.Score > found[match.Index].score {
+				found[match.Index] = duplicate{task: tasks[match.Index], score: match.Score}
+			}
+		}
+	}
+
+	tickets := similar.NewTickets(ticketPrefixes(projects))
+	var keys []string
+	for _, desc := range descriptions {
+		keys = append(keys, tickets.Keys(desc)...)
+	}
+	if len(keys) > 0 {
+		for i, t := range tasks {
+			for _, k := range tickets.Keys(ticketText(t)) {
+				if slices.Contains(keys, k) {
+					d := found[i]
+					d.task, d.key = t, k
+					found[i] = d
+					break
+				}
+			}
+		}
+	}
+
+	var duplicates []duplicate
+	for _, d := range found {
+		duplicates = append(duplicates, d)
+	}
+	slices.SortFunc(duplicates, func(a, b duplicate) int {
+		if (a.key != "") != (b.key != "") {
+			if a.key != "" {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(b.score, a.score), cmp.Compare(a.task.ID, b.task.ID))
+	})
+	return duplicates[:min(len(duplicates), maxDuplicates)]
+}
+
+// ticketPrefixes are the project keywords naming issue keys, like "JIRA-"
+func ticketPrefixes(projects []config.Project) []string {
+	var prefixes []string
+	for _, p := range projects {
+		for _, kw := range p.Keywords {
+			if len(kw) > 1 && strings.HasSuffix(kw, "-") {
+				prefixes = append(prefixes, kw)
+			}
+		}
+	}
+	return prefixes
+}
+
+// ticketText is where a task may mention its ticket: bugwarrior puts the key in the
+// description, an annotation or a UDA such as the issue URL
+func ticketText(t taskwarrior.Task) string {
+	parts := []string{t.Description}
+	for _, a := range t.Annotations {
+		parts = append(parts, a.Description)
+	}
+	for _, v := range t.UDAs {
+		parts = append(parts, v)
+	}
+	return strings.Join(parts, "\n")
+}
+
+func (m *AddModel) handleDuplicates(msg duplicatesMsg) (tea.Model, tea.Cmd) {
+	if len(msg) == 0 {
+		return m, m.addTask()
+	}
+	m.duplicates = msg
+	m.duplicateCursor = 0
+	m.state = stateDuplicates
+	return m, nil
+}
+
+func (m *AddModel) handleDuplicateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		m.skipEnrich = false
+		m.state = statePreview
+	case "up", "k":
+		m.duplicateCursor = max(m.duplicateCursor-1, 0)
+	case "down", "j":
+		m.duplicateCursor = min(m.duplicateCursor+1, len(m.duplicates)-1)
+	case "m":
+		return m, m.mergeInto(m.duplicates[m.duplicateCursor].task)
+	case "a":
+		return m, m.addTask()
+	}
+	return m, nil
+}
+
+// mergeAnnotations is what merging attaches to the existing task: the new
+// description unless it says the same, and the annotations selected in the preview
+func (m *AddModel) mergeAnnotations(existing taskwarrior.Task) []string {
+	description := m.original
+	var annotations []string
+	if !m.skipEnrich {
+		description = m.selection.apply(m.enrichment).Description
+		annotations = m.selection.annotations()
+	}
+	if !strings.EqualFold(strings.TrimSpace(description), strings.TrimSpace(existing.Description)) {
+		annotations = append([]string{description}, annotations...)
+	}
+	return annotations
+}
+
+// mergeInto annotates the existing task instead of adding a new one
+func (m *AddModel) mergeInto(existing taskwarrior.Task) tea.Cmd {
+	annotations := m.mergeAnnotations(existing)
+	return func() tea.Msg {
+		return mergedMsg{task: existing, err: m.twClient.Annotate(existing.UUID, annotations...)}
+	}
+}
+
+func (m *AddModel) viewDuplicates() string {
+	var sb strings.Builder
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+
+	sb.WriteString(titleStyle.Render("tg add - possible duplicate") + "\n\n")
+	description := m.original
+	if !m.skipEnrich {
+		description = m.selection.apply(m.enrichment).Description
+	}
+	sb.WriteString(labelStyle.Render("Adding:") + " " + valueStyle.Render(description) + "\n\n")
+
+	var content strings.Builder
+	content.WriteString(subtitleStyle.Render("Pending tasks that look the same:") + "\n\n")
+	for i, d := range m.duplicates {
+		cursor := "  "
+		desc := valueStyle.Render(d.task.Description)
+		if i == m.duplicateCursor {
+			cursor = selectedStyle.Render("› ")
+			desc = selectedStyle.Render(d.task.Description)
+		}
+		id := ""
+		if d.task.ID > 0 {
+			id = muted.Render(fmt.Sprintf("%d ", d.task.ID))
+		}
+		content.WriteString(cursor + id + desc + "\n")
+
+		reason := muted.Render(fmt.Sprintf("%.0f%% similar", d.score*100))
+		if d.key != "" {
+			reason = warningStyle.Render("same ticket " + d.key)
+		}
+		if d.task.Project != "" {
+			reason += muted.Render(" · project " + d.task.Project)
+		}
+		content.WriteString("    " + reason + "\n")
+	}
+
+	annotations := m.mergeAnnotations(m.duplicates[m.duplicateCursor].task)
+	if len(annotations) > 0 {
+		content.WriteString("\n" + subtitleStyle.Render("Merging annotates it with:") + "\n")
+		for _, a := range annotations {
+			content.WriteString(muted.Render("  "+truncateText(a, 70)) + "\n")
+		}
+	}
+
+	sb.WriteString(boxStyle.Render(strings.TrimSuffix(content.String(), "\n")))
+	sb.WriteString("\n\n")
+	sb.WriteString(helpStyle.Render("[↑/↓] Move  [m] Merge into selected  [a] Add anyway  [esc] Back  [q] Cancel"))
+
+	return sb.String()
+}
@@ -6,13 +6,14 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/bubbles/spinner"
-	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/enrich"
 	"github.com/bf/tg/internal/llm"
 	"github.com/bf/tg/internal/taskwarrior"
+	"github.com/bf/tg/internal/usage"
 )
 
 type enrichState int
@@ -29,18 +30,35 @@ const (
 type EnrichModel struct {
 	cfg        *config.Config
 	provider   llm.Provider
+	meter      *llm.Meter // the provider's, for the budget and the cost so far
 	twClient   *taskwarrior.Client
 	filter     string
 	tasks      []taskwarrior.Task
 	current    int
 	enrichment *llm.Enrichment
+	suggested  *llm.Enrichment // the enrichment as the model suggested it, for the corrections log
+	fixes      []llm.Fix
+	selection  *selection
+	dates      map[string]dateResult // resolved due/scheduled expressions
+	notice     string                // shown in edit mode, e.g. why the modify was refused
 	state      enrichState
 	spinner    spinner.Model
 	err        error
 	processed  int
 	skipped    int
+	dryRun     bool
+	plan       []string // task modify commands collected in dry-run mode
+	budget     float64  // estimated USD after which no more tasks are enriched, 0 for no limit
+	overBudget bool     // the batch stopped at the budget
+	// Prefetching: the next lookahead tasks are enriched in the background
+	ctx       context.Context
+	cancel    context.CancelFunc
+	lookahead int
+	results   map[string]taskEnrichedMsg // finished enrichments by task UUID
+	inFlight  map[string]bool
+	progress  map[string]llm.Progress
 	// Edit mode
-	textInputs []textinput.Model
+	editors    []fieldEditor
 	fieldNames []string
 	editField  int
 }
@@ -51,42 +69,68 @@ type tasksLoadedMsg struct {
 }
 
 type taskEnrichedMsg struct {
+	uuid       string
 	enrichment *llm.Enrichment
 	err        error
 }
 
+type taskProgressMsg struct {
+	uuid     string
+	progress llm.Progress
+	ch       <-chan llm.Progress
+}
+
 type taskModifiedMsg struct {
-	err error
+	command string // the modify command recorded instead of run, in dry-run mode
+	err     error
 }
 
-func NewEnrichModel(cfg *config.Config, provider llm.Provider, filter string) *EnrichModel {
+func NewEnrichModel(cfg *config.Config, provider llm.Provider, meter *llm.Meter, filter string) *EnrichModel {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = spinnerStyle
 
-	// Create text inputs for editing (no Description - preserved from bugwarrior)
+	// Edit mode form (no Description - preserved from bugwarrior)
 	fields := []string{"Beacons", "Directions", "Project", "Priority", "Due", "Scheduled", "Effort", "Impact", "Estimate", "Fun", "Blocks"}
-	inputs := make([]textinput.Model, len(fields))
-	for i := range inputs {
-		ti := textinput.New()
-		ti.Prompt = ""
-		ti.CharLimit = 256
-		inputs[i] = ti
-	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 
 	return &EnrichModel{
 		cfg:        cfg,
 		provider:   provider,
+		meter:      meter,
 		twClient:   taskwarrior.New(),
 		filter:     filter,
 		state:      enrichStateLoading,
 		spinner:    s,
-		textInputs: inputs,
+		ctx:        ctx,
+		cancel:     cancel,
+		lookahead:  cfg.Enrich.Lookahead,
+		budget:     cfg.Enrich.Budget,
+		results:    make(map[string]taskEnrichedMsg),
+		inFlight:   make(map[string]bool),
+		progress:   make(map[string]llm.Progress),
+		dates:      make(map[string]dateResult),
+		editors:    newFieldEditors(cfg, fields),
 		fieldNames: fields,
 	}
 }
 
+// NewEnrichReviewModel reviews tasks that already have enrichments, e.g. the
+// low-confidence leftovers of `tg enrich --auto`
+func NewEnrichReviewModel(cfg *config.Config, provider llm.Provider, meter *llm.Meter, tasks []taskwarrior.Task, enrichments map[string]*llm.Enrichment) *EnrichModel {
+	m := NewEnrichModel(cfg, provider, meter, "")
+	m.tasks = tasks
+	for uuid, enrichment := range enrichments {
+		m.results[uuid] = taskEnrichedMsg{uuid: uuid, enrichment: enrichment}
+	}
+	return m
+}
+
 func (m *EnrichModel) Init() tea.Cmd {
+	if len(m.tasks) > 0 {
+		return m.showCurrent()
+	}
 	return tea.Batch(
 		m.spinner.Tick,
 		m.loadTasks(),
@@ -108,54 +152,123 @@ func (m *EnrichModel) loadTasks() tea.Cmd {
 	}
 }
 
-func (m *EnrichModel) enrichCurrent() tea.Cmd {
-	if m.current >= len(m.tasks) {
+// prefetch starts enrichments for the current task and the next lookahead ones,
+// skipping tasks that are already done or in flight. Nothing new starts once the
+// budget is spent.
+func (m *EnrichModel) prefetch() tea.Cmd {
+	if m.spentBudget() {
 		return nil
 	}
+	var cmds []tea.Cmd
+	last := min(m.current+m.lookahead, len(m.tasks)-1)
+	for i := m.current; i <= last; i++ {
+		task := m.tasks[i]
+		if _, done := m.results[task.UUID]; done || m.inFlight[task.UUID] {
+			continue
+		}
+		m.inFlight[task.UUID] = true
+		cmds = append(cmds, m.enrichTask(task))
+	}
+	return tea.Batch(cmds...)
+}
 
-	task := m.tasks[m.current]
-	return func() tea.Msg {
+func (m *EnrichModel) enrichTask(task taskwarrior.Task) tea.Cmd {
+	progress := make(chan llm.Progress, 1)
+	fetch := func() tea.Msg {
+		defer close(progress)
 		enrichment, err := m.provider.Enrich(
-			context.Background(),
-			task.Description,
+			llm.WithProgress(m.ctx, relayProgress(progress)),
+			task,
 			m.cfg.Beacons,
 			m.cfg.Projects,
 		)
-		return taskEnrichedMsg{enrichment: enrichment, err: err}
+		return taskEnrichedMsg{uuid: task.UUID, enrichment: enrichment, err: err}
 	}
+	return tea.Batch(fetch, listenTaskProgress(task.UUID, progress))
 }
 
-func (m *EnrichModel) applyEnrichment() tea.Cmd {
-	task := m.tasks[m.current]
-	enrichment := m.enrichment
-
+// listenTaskProgress is listenProgress for one of several concurrent enrichments
+func listenTaskProgress(uuid string, ch <-chan llm.Progress) tea.Cmd {
 	return func() tea.Msg {
-		modified := &taskwarrior.Task{
-			Description: enrichment.Description,
-			Priority:    enrichment.Priority,
-			Due:         enrichment.Due,
-			Scheduled:   enrichment.Scheduled,
-			Effort:      enrichment.Effort,
-			Impact:      enrichment.Impact,
-			Estimate:    enrichment.Estimate,
-			Fun:         enrichment.Fun,
-			Blocks:      enrichment.Blocks,
+		p, ok := <-ch
+		if !ok {
+			return nil
 		}
+		return taskProgressMsg{uuid: uuid, progress: p, ch: ch}
+	}
+}
 
-		// Only set project if task doesn't already have one
-		// This preserves bugwarrior-synced projects and prevents accidental overwrites
-		if task.Project == "" {
-			modified.Project = enrichment.Project
-		}
+// showCurrent previews the current task if its enrichment is ready, or waits for it
+// otherwise; either way it tops up the prefetch window
+func (m *EnrichModel) showCurrent() tea.Cmd {
+	prefetch := m.prefetch()
 
-		modified.Tags = append(modified.Tags, enrichment.Beacons...)
-		modified.Tags = append(modified.Tags, enrichment.Directions...)
+	result, ok := m.results[m.tasks[m.current].UUID]
+	if !ok && !m.inFlight[m.tasks[m.current].UUID] && m.spentBudget() {
+		m.overBudget = true
+		m.state = enrichStateDone
+		return m.quit()
+	}
+	if !ok {
+		m.state = enrichStateFetching
+		return tea.Batch(m.spinner.Tick, prefetch)
+	}
 
-		if enrichment.IsWaste {
-			modified.Tags = append(modified.Tags, "waste")
+	if result.err != nil {
+		m.err = result.err
+		m.state = enrichStateError
+		return nil
+	}
+	m.enrichment = result.enrichment
+	m.fixes = llm.Validate(m.enrichment, m.cfg)
+	suggested := *m.enrichment
+	m.suggested = &suggested
+	m.selection = newSelection(m.enrichment, m.cfg)
+	m.state = enrichStatePreview
+	return tea.Batch(prefetch, resolveDates(m.twClient, m.dates, m.enrichment))
+}
+
+// quit cancels any prefetches still in flight
+func (m *EnrichModel) quit() tea.Cmd {
+	m.cancel()
+	return tea.Quit
+}
+
+// spentBudget reports whether the LLM calls so far used up the budget
+func (m *EnrichModel) spentBudget() bool {
+	return m.budget > 0 && m.meter.Spent() >= m.budget
+}
+
+// SetDryRun makes accepting a task record its modify command instead of running it
+func (m *EnrichModel) SetDryRun(dryRun bool) {
+	m.dryRun = dryRun
+}
+
+// Plan returns the modify commands accepted in dry-run mode
+func (m *EnrichModel) Plan() []string {
+	return m.plan
+}
+
+func (m *EnrichModel) applyEnrichment() tea.Cmd {
+	task := m.tasks[m.current]
+	enrichment := m.selection.apply(m.enrichment)
+
+	if m.dryRun {
+		return func() tea.Msg {
+			args, err := m.twClient.ModifyArgs(task.UUID, enrich.Modification(task, enrichment))
+			if err != nil {
+				return taskModifiedMsg{err: err}
+			}
+			return taskModifiedMsg{command: taskwarrior.CommandLine(args)}
 		}
+	}
 
-		err := m.twClient.Modify(task.UUID, modified)
+	suggested := m.suggested
+	return func() tea.Msg {
+		err := m.twClient.Modify(task.UUID, enrich.Modification(task, enrichment))
+		if err == nil {
+			recordCorrection(task.Description, suggested, enrichment)
+		}
 		return taskModifiedMsg{err: err}
 	}
 }
@@ -181,27 +294,40 @@ func (m *EnrichModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.tasks = msg.tasks
 		if len(m.tasks) == 0 {
 			m.state = enrichStateDone
-			return m, tea.Quit
+			return m, m.quit()
 		}
-		m.state = enrichStateFetching
-		return m, tea.Batch(m.spinner.Tick, m.enrichCurrent())
+		return m, m.showCurrent()
+
+	case taskProgressMsg:
+		m.progress[msg.uuid] = msg.progress
+		return m, listenTaskProgress(msg.uuid, msg.ch)
 
 	case taskEnrichedMsg:
-		if msg.err != nil {
-			m.err = msg.err
-			m.state = enrichStateError
-			return m, nil
+		delete(m.inFlight, msg.uuid)
+		m.results[msg.uuid] = msg
+		if m.state == enrichStateFetching && msg.uuid == m.tasks[m.current].UUID {
+			return m, m.showCurrent()
+		}
+		return m, nil
+
+	case datesResolvedMsg:
+		for expr, r := range msg {
+			m.dates[expr] = r
 		}
-		m.enrichment = msg.enrichment
-		m.state = enrichStatePreview
 		return m, nil
 
 	case taskModifiedMsg:
+		if m.editDate(msg.err) {
+			return m, nil
+		}
 		if msg.err != nil {
 			m.err = msg.err
 			m.state = enrichStateError
 			return m, nil
 		}
+		if msg.command != "" {
+			m.plan = append(m.plan, msg.command)
+		}
 		m.processed++
 		return m, m.nextTask()
 	}
@@ -213,119 +339,152 @@ func (m *EnrichModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch m.state {
 	case enrichStateLoading, enrichStateFetching:
 		if msg.String() == "ctrl+c" || msg.String() == "esc" {
-			return m, tea.Quit
+			return m, m.quit()
 		}
 
 	case enrichStatePreview:
 		switch msg.String() {
 		case "ctrl+c", "q":
-			return m, tea.Quit
+			return m, m.quit()
 		case "esc":
 			// Done early
 			m.state = enrichStateDone
-			return m, tea.Quit
+			return m, m.quit()
 		case "enter", "a":
-			// Accept and apply
+			// Accept and apply, unless a selected date is known to be invalid
+			if m.editDate(invalidDate(m.selection.apply(m.enrichment), m.dates)) {
+				return m, nil
+			}
 			return m, m.applyEnrichment()
 		case "e":
-			// Enter edit mode
-			m.populateInputs()
-			m.state = enrichStateEditing
-			m.editField = 0
-			m.textInputs[0].Focus()
+			// Enter edit mode with only the selected values
+			m.startEditing(0)
 			return m, nil
 		case "s", "n":
 			// Skip this task
 			m.skipped++
 			return m, m.nextTask()
+		default:
+			m.selection.handleKey(msg.String())
+			return m, nil
 		}
 
 	case enrichStateEditing:
 		switch msg.String() {
 		case "ctrl+c":
-			return m, tea.Quit
+			return m, m.quit()
 		case "esc":
 			// Exit edit mode back to preview
-			m.state = enrichStatePreview
-			return m, nil
+			return m, m.stopEditing()
 		case "enter":
 			// Save and go to next field or exit edit mode
 			m.updateEnrichmentFromInputs()
-			if m.editField < len(m.textInputs)-1 {
-				m.textInputs[m.editField].Blur()
+			if m.editField < len(m.editors)-1 {
+				m.editors[m.editField].Blur()
 				m.editField++
-				m.textInputs[m.editField].Focus()
+				m.editors[m.editField].Focus()
 			} else {
-				m.state = enrichStatePreview
+				return m, m.stopEditing()
 			}
 			return m, nil
 		case "tab":
-			m.textInputs[m.editField].Blur()
-			m.editField = (m.editField + 1) % len(m.textInputs)
-			m.textInputs[m.editField].Focus()
+			m.editors[m.editField].Blur()
+			m.editField = (m.editField + 1) % len(m.editors)
+			m.editors[m.editField].Focus()
 			return m, nil
 		case "shift+tab":
-			m.textInputs[m.editField].Blur()
-			m.editField = (m.editField - 1 + len(m.textInputs)) % len(m.textInputs)
-			m.textInputs[m.editField].Focus()
+			m.editors[m.editField].Blur()
+			m.editField = (m.editField - 1 + len(m.editors)) % len(m.editors)
+			m.editors[m.editField].Focus()
 			return m, nil
 		default:
 			// Update current text input
-			var cmd tea.Cmd
-			m.textInputs[m.editField], cmd = m.textInputs[m.editField].Update(msg)
-			return m, cmd
+			return m, m.editors[m.editField].Update(msg)
 		}
 
 	case enrichStateError, enrichStateDone:
-		return m, tea.Quit
+		return m, m.quit()
 	}
 
 	return m, nil
 }
 
 func (m *EnrichModel) nextTask() tea.Cmd {
+	delete(m.results, m.tasks[m.current].UUID)
+	delete(m.progress, m.tasks[m.current].UUID)
 	m.current++
 	if m.current >= len(m.tasks) {
 		m.state = enrichStateDone
-		return tea.Quit
+		return m.quit()
 	}
-	m.state = enrichStateFetching
 	m.enrichment = nil
-	return tea.Batch(m.spinner.Tick, m.enrichCurrent())
+	m.suggested = nil
+	m.fixes = nil
+	m.selection = nil
+	return m.showCurrent()
+}
+
+// startEditing enters edit mode on the given field with only the selected values
+func (m *EnrichModel) startEditing(field int) {
+	m.enrichment = m.selection.apply(m.enrichment)
+	m.populateInputs()
+	m.state = enrichStateEditing
+	m.editors[m.editField].Blur()
+	m.editField = field
+	m.editors[field].Focus()
+}
+
+// stopEditing returns to the preview and resolves any edited dates
+func (m *EnrichModel) stopEditing() tea.Cmd {
+	m.notice = ""
+	m.selection = newSelection(m.enrichment, m.cfg)
+	m.state = enrichStatePreview
+	return resolveDates(m.twClient, m.dates, m.enrichment)
+}
+
+// editDate sends the user to the date field err complains about, if it is a date error
+func (m *EnrichModel) editDate(err error) bool {
+	field, ok := dateField(err, m.fieldNames)
+	if !ok {
+		return false
+	}
+	m.startEditing(field)
+	m.notice = err.Error()
+	return true
 }
 
 func (m *EnrichModel) populateInputs() {
 	if m.enrichment == nil {
 		return
 	}
-	m.textInputs[0].SetValue(strings.Join(m.enrichment.Beacons, " "))
-	m.textInputs[1].SetValue(strings.Join(m.enrichment.Directions, " "))
-	m.textInputs[2].SetValue(m.enrichment.Project)
-	m.textInputs[3].SetValue(m.enrichment.Priority)
-	m.textInputs[4].SetValue(m.enrichment.Due)
-	m.textInputs[5].SetValue(m.enrichment.Scheduled)
-	m.textInputs[6].SetValue(m.enrichment.Effort)
-	m.textInputs[7].SetValue(m.enrichment.Impact)
-	m.textInputs[8].SetValue(m.enrichment.Estimate)
-	m.textInputs[9].SetValue(m.enrichment.Fun)
-	m.textInputs[10].SetValue(fmt.Sprintf("%d", m.enrichment.Blocks))
+	m.editors[0].SetValue(strings.Join(m.enrichment.Beacons, " "))
+	m.editors[1].SetValue(strings.Join(m.enrichment.Directions, " "))
+	m.editors[2].SetValue(m.enrichment.Project)
+	m.editors[3].SetValue(m.enrichment.Priority)
+	m.editors[4].SetValue(m.enrichment.Due)
+	m.editors[5].SetValue(m.enrichment.Scheduled)
+	m.editors[6].SetValue(m.enrichment.Effort)
+	m.editors[7].SetValue(m.enrichment.Impact)
+	m.editors[8].SetValue(m.enrichment.Estimate)
+	m.editors[9].SetValue(m.enrichment.Fun)
+	m.editors[10].SetValue(blocksValue(m.enrichment.Blocks))
 }
 
 func (m *EnrichModel) updateEnrichmentFromInputs() {
 	if m.enrichment == nil {
 		m.enrichment = &llm.Enrichment{}
 	}
-	m.enrichment.Beacons = splitTags(m.textInputs[0].Value())
-	m.enrichment.Directions = splitTags(m.textInputs[1].Value())
-	m.enrichment.Project = m.textInputs[2].Value()
-	m.enrichment.Priority = m.textInputs[3].Value()
-	m.enrichment.Due = m.textInputs[4].Value()
-	m.enrichment.Scheduled = m.textInputs[5].Value()
-	m.enrichment.Effort = m.textInputs[6].Value()
-	m.enrichment.Impact = m.textInputs[7].Value()
-	m.enrichment.Estimate = m.textInputs[8].Value()
-	m.enrichment.Fun = m.textInputs[9].Value()
-	m.enrichment.Blocks = parseBlocks(m.textInputs[10].Value())
+	m.enrichment.Beacons = splitTags(m.editors[0].Value())
+	m.enrichment.Directions = splitTags(m.editors[1].Value())
+	m.enrichment.Project = m.editors[2].Value()
+	m.enrichment.Priority = m.editors[3].Value()
+	m.enrichment.Due = m.editors[4].Value()
+	m.enrichment.Scheduled = m.editors[5].Value()
+	m.enrichment.Effort = m.editors[6].Value()
+	m.enrichment.Impact = m.editors[7].Value()
+	m.enrichment.Estimate = m.editors[8].Value()
+	m.enrichment.Fun = m.editors[9].Value()
+	m.enrichment.Blocks = parseBlocks(m.editors[10].Value())
 }
 
 func (m *EnrichModel) View() string {
@@ -353,10 +512,11 @@ func (m *EnrichModel) viewLoading() string {
 
 func (m *EnrichModel) viewFetching() string {
 	task := m.tasks[m.current]
-	return fmt.Sprintf("\n  %s Enriching task %d/%d...\n\n  %s\n",
+	return fmt.Sprintf("\n  %s Enriching task %d/%d...%s\n\n  %s\n",
 		m.spinner.View(),
 		m.current+1,
 		len(m.tasks),
+		formatAttempt(m.progress[task.UUID]),
 		subtitleStyle.Render(task.Description),
 	)
 }
@@ -365,7 +525,11 @@ func (m *EnrichModel) viewPreview() string {
 	var sb strings.Builder
 	task := m.tasks[m.current]
 
-	sb.WriteString(titleStyle.Render(fmt.Sprintf("tg enrich (%d/%d)", m.current+1, len(m.tasks))) + "\n\n")
+	title := fmt.Sprintf("tg enrich (%d/%d)", m.current+1, len(m.tasks))
+	if m.dryRun {
+		title += " - dry run"
+	}
+	sb.WriteString(titleStyle.Render(title) + " " + subtitleStyle.Render(m.formatSpent()) + "\n\n")
 	sb.WriteString(labelStyle.Render("Task:") + " " + subtitleStyle.Render(task.Description) + "\n")
 
 	if task.Project != "" {
@@ -373,6 +537,11 @@ func (m *EnrichModel) viewPreview() string {
 	}
 	sb.WriteString("\n")
 
+	if m.enrichment.Cached {
+		sb.WriteString(cachedTagStyle.Render("CACHED") + " " + subtitleStyle.Render("Served from the local cache, no LLM call") + "\n\n")
+	}
+	sb.WriteString(formatProvider(m.enrichment))
+
 	if m.enrichment.IsWaste {
 		sb.WriteString(wasteTagStyle.Render(" WASTE ") + " " + subtitleStyle.Render("This task doesn't align with any beacon") + "\n\n")
 	}
@@ -383,48 +552,18 @@ func (m *EnrichModel) viewPreview() string {
 	// Note: Description is shown for context but won't be modified (preserves bugwarrior sync)
 	content.WriteString(labelStyle.Render("Description:") + " " + lipgloss.NewStyle().Foreground(mutedColor).Render("(unchanged)") + "\n")
 
-	// Beacons
-	content.WriteString(labelStyle.Render("Beacons:") + " ")
-	if len(m.enrichment.Beacons) > 0 {
-		tags := make([]string, len(m.enrichment.Beacons))
-		for i, b := range m.enrichment.Beacons {
-			tags[i] = tagStyle.Render(b)
-		}
-		content.WriteString(strings.Join(tags, " "))
-	} else {
-		content.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render("none"))
-	}
-	content.WriteString("\n")
-
-	// Directions
-	content.WriteString(labelStyle.Render("Directions:") + " ")
-	if len(m.enrichment.Directions) > 0 {
-		tags := make([]string, len(m.enrichment.Directions))
-		for i, d := range m.enrichment.Directions {
-			tags[i] = directionTagStyle.Render(d)
-		}
-		content.WriteString(strings.Join(tags, " "))
-	} else {
-		content.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render("none"))
-	}
-	content.WriteString("\n")
+	// Suggested values, each of which can be accepted or rejected
+	content.WriteString("\n" + m.selection.view(m.dates) + "\n")
 
-	// Show project with preservation indicator
-	if task.Project != "" {
-		content.WriteString(labelStyle.Render("Project:") + " " + valueStyle.Render(task.Project) + " " + lipgloss.NewStyle().Foreground(mutedColor).Render("(preserved)") + "\n")
-	} else {
-		content.WriteString(labelStyle.Render("Project:") + " " + valueOrNone(m.enrichment.Project) + "\n")
-	}
-	content.WriteString(labelStyle.Render("Priority:") + " " + valueOrNone(m.enrichment.Priority) + "\n")
-	content.WriteString(labelStyle.Render("Due:") + " " + valueOrNone(m.enrichment.Due) + " " + lipgloss.NewStyle().Foreground(mutedColor).Render("(hard deadline)") + "\n")
-	content.WriteString(labelStyle.Render("Scheduled:") + " " + valueOrNone(m.enrichment.Scheduled) + " " + lipgloss.NewStyle().Foreground(mutedColor).Render("(soft due date)") + "\n")
+	// Field-by-field diff against the task as it is now
+	content.WriteString("\n" + formatDiff(enrich.Diff(task, enrich.Modification(task, m.selection.apply(m.enrichment)))) + "\n")
+	content.WriteString(labelStyle.Render("Confidence:") + " " + formatConfidence(m.enrichment.Confidence) + "\n")
+	content.WriteString(formatUsage(m.enrichment.Usage))
 
-	// UDAs: Effort, Impact, Estimate, Fun, Blocks
-	content.WriteString(labelStyle.Render("Effort:") + " " + formatUDA(m.enrichment.Effort, "E=Easy N=Normal D=Difficult") + "\n")
-	content.WriteString(labelStyle.Render("Impact:") + " " + formatUDA(m.enrichment.Impact, "H=High M=Medium L=Low") + "\n")
-	content.WriteString(labelStyle.Render("Estimate:") + " " + valueOrNone(m.enrichment.Estimate) + "\n")
-	content.WriteString(labelStyle.Render("Fun:") + " " + formatUDA(m.enrichment.Fun, "H=Fun M=Neutral L=Boring") + "\n")
-	content.WriteString(labelStyle.Render("Blocks:") + " " + formatBlocks(m.enrichment.Blocks) + "\n")
+	// Values the validator repaired or flagged
+	if len(m.fixes) > 0 {
+		content.WriteString("\n" + formatFixes(m.fixes))
+	}
 
 	if m.enrichment.Reasoning != "" {
 		content.WriteString("\n" + subtitleStyle.Render(m.enrichment.Reasoning))
@@ -432,17 +571,58 @@ func (m *EnrichModel) viewPreview() string {
 
 	sb.WriteString(boxStyle.Render(content.String()))
 	sb.WriteString("\n\n")
-	sb.WriteString(helpStyle.Render("[enter/a] Accept  [e] Edit  [s/n] Skip  [esc/q] Done"))
+	sb.WriteString(helpStyle.Render("[↑/↓] Move  [space] Toggle  [←/→] Change  [enter/a] Accept selected  [e] Edit  [s/n] Skip  [esc/q] Done"))
 
 	return sb.String()
 }
 
+// formatDiff renders current and proposed values side by side: additions in green,
+// overwrites in orange, untouched fields muted
+func formatDiff(changes []enrich.Change) string {
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+	added := lipgloss.NewStyle().Foreground(successColor)
+	currentCol := lipgloss.NewStyle().Width(20)
+
+	var sb strings.Builder
+	sb.WriteString(labelStyle.Render("") + " " + currentCol.Render(muted.Render("current")) + "   " + muted.Render("proposed") + "\n")
+	for _, c := range changes {
+		current := muted.Render("--")
+		if c.Current != "" {
+			current = valueStyle.Render(truncateText(c.Current, 19))
+		}
+
+		var proposed string
+		switch c.Kind {
+		case enrich.Added:
+			proposed = added.Render("+ " + c.Proposed)
+		case enrich.Overwritten:
+			proposed = warningStyle.Render("~ " + c.Proposed)
+		default:
+			proposed = muted.Render("(kept)")
+		}
+
+		sb.WriteString(labelStyle.Render(c.Field+":") + " " + currentCol.Render(current) + " → " + proposed + "\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func truncateText(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
 func (m *EnrichModel) viewEditing() string {
 	var sb strings.Builder
 	task := m.tasks[m.current]
 
 	sb.WriteString(titleStyle.Render(fmt.Sprintf("tg enrich - Edit Mode (%d/%d)", m.current+1, len(m.tasks))) + "\n\n")
 	sb.WriteString(labelStyle.Render("Task:") + " " + subtitleStyle.Render(task.Description) + "\n\n")
+	if m.notice != "" {
+		sb.WriteString(warningStyle.Render("! "+m.notice) + "\n\n")
+	}
 
 	for i, name := range m.fieldNames {
 		style := labelStyle
@@ -450,17 +630,41 @@ func (m *EnrichModel) viewEditing() string {
 			style = selectedStyle
 		}
 		sb.WriteString(style.Render(name+":") + " ")
-		sb.WriteString(m.textInputs[i].View())
+		sb.WriteString(m.editors[i].View(i == m.editField))
 		sb.WriteString("\n")
 	}
 
 	sb.WriteString("\n")
-	sb.WriteString(helpStyle.Render("[tab] Next  [shift+tab] Prev  [enter] Save  [esc] Back"))
+	sb.WriteString(helpStyle.Render("[tab] Next  [shift+tab] Prev  [←/→] Pick value  [space] Check tag  [enter] Save  [esc] Back"))
 
 	return sb.String()
 }
 
+// formatSpent shows the estimated cost of the batch so far, against the budget if set
+func (m *EnrichModel) formatSpent() string {
+	spent := "spent " + usage.FormatCost(m.meter.Spent())
+	if m.budget > 0 {
+		spent += " of " + usage.FormatCost(m.budget)
+	}
+	return spent
+}
+
 func (m *EnrichModel) viewDone() string {
+	if m.overBudget {
+		return fmt.Sprintf("\n%s\n  Processed: %d  Skipped: %d  Not enriched: %d\n",
+			warningStyle.Render("Stopped at the budget of "+usage.FormatCost(m.budget)),
+			m.processed,
+			m.skipped,
+			len(m.tasks)-m.current,
+		)
+	}
+	if m.dryRun {
+		return fmt.Sprintf("\n%s\n  Planned: %d  Skipped: %d\n",
+			successStyle.Render("Dry run complete, nothing was modified"),
+			m.processed,
+			m.skipped,
+		)
+	}
 	return fmt.Sprintf("\n%s\n  Processed: %d  Skipped: %d\n",
 		successStyle.Render("Batch enrichment complete!"),
 		m.processed,
@@ -0,0 +1,344 @@
+package tui
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/bf/tg/internal/config"
+)
+
+// fieldEditor is one field of the edit mode form
+type fieldEditor interface {
+	Focus()
+	Blur()
+	Update(msg tea.Msg) tea.Cmd
+	View(focused bool) string
+	Value() string
+	SetValue(s string)
+}
+
+// newFieldEditors builds the editor for each named field: free text for the
+// description, dates and unconfigured projects, pickers for fields with a fixed
+// set of values and checklists for tags
+func newFieldEditors(cfg *config.Config, fields []string) []fieldEditor {
+	var beacons, directions []checkOption
+	for _, b := range cfg.Beacons {
+		beacons = append(beacons, checkOption{tag: b.Tag, name: b.Name})
+		for _, d := range b.Directions {
+			if !slices.ContainsFunc(directions, func(o checkOption) bool { return o.tag == d.Tag }) {
+				directions = append(directions, checkOption{tag: d.Tag, name: d.Name})
+			}
+		}
+	}
+	var projects []string
+	for _, p := range cfg.Projects {
+		projects = append(projects, p.Name)
+	}
+	blocks := make([]string, 11)
+	for i := range blocks {
+		blocks[i] = strconv.Itoa(i)
+	}
+
+	allowed := cfg.UDAValues
+	editors := make([]fieldEditor, len(fields))
+	for i, name := range fields {
+		switch name {
+		case "Beacons":
+			editors[i] = newChecklist(beacons)
+		case "Directions":
+			editors[i] = newChecklist(directions)
+		case "Project":
+			// Without configured projects there is nothing to pick from, so type it
+			if len(projects) == 0 {
+				editors[i] = newTextField()
+			} else {
+				editors[i] = newPicker(projects)
+			}
+		case "Priority":
+			editors[i] = newPicker(allowed.Priority)
+		case "Effort":
+			editors[i] = newPicker(allowed.Effort)
+		case "Impact":
+			editors[i] = newPicker(allowed.Impact)
+		case "Estimate":
+			editors[i] = newPicker(allowed.Estimate)
+		case "Fun":
+			editors[i] = newPicker(allowed.Fun)
+		case "Blocks":
+			editors[i] = newPicker(blocks)
+		default:
+			editors[i] = newTextField()
+		}
+	}
+	return editors
+}
+
+// textField is a free-text editor
+type textField struct {
+	input textinput.Model
+}
+
+func newTextField() *textField {
+	ti := textinput.New()
+	ti.Prompt = ""
+	ti.CharLimit = 256
+	return &textField{input: ti}
+}
+
+func (f *textField) Focus()            { f.input.Focus() }
+func (f *textField) Blur()             { f.input.Blur() }
+func (f *textField) Value() string     { return f.input.Value() }
+func (f *textField) SetValue(s string) { f.input.SetValue(s) }
+func (f *textField) View(bool) string  { return f.input.View() }
+func (f *textField) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	f.input, cmd = f.input.Update(msg)
+	return cmd
+}
+
+// picker selects one of a fixed set of values, or none. Left/right cycle through
+// them, typing jumps to the next value starting with the typed character and
+// backspace clears the field.
+type picker struct {
+	choices  []string
+	selected int // index into choices, -1 for none
+}
+
+func newPicker(choices []string) *picker {
+	return &picker{choices: choices, selected: -1}
+}
+
+func (p *picker) Focus() {}
+func (p *picker) Blur()  {}
+
+func (p *picker) Value() string {
+	if p.selected < 0 {
+		return ""
+	}
+	return p.choices[p.selected]
+}
+
+// SetValue selects s; a value outside the choices is added so it isn't lost
+func (p *picker) SetValue(s string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		p.selected = -1
+		return
+	}
+	p.selected = slices.Index(p.choices, s)
+	if p.selected < 0 {
+		p.choices = append(slices.Clone(p.choices), s)
+		p.selected = len(p.choices) - 1
+	}
+}
+
+func (p *picker) Update(msg tea.Msg) tea.Cmd {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok || len(p.choices) == 0 {
+		return nil
+	}
+
+	// Position 0 is "none", the choices follow
+	pos, n := p.selected+1, len(p.choices)+1
+	switch key.String() {
+	case "left":
+		p.selected = (pos-1+n)%n - 1
+	case "right", " ":
+		p.selected = (pos+1)%n - 1
+	case "backspace", "delete":
+		p.selected = -1
+	default:
+		if len(key.Runes) != 1 {
+			return nil
+		}
+		typed := strings.ToLower(string(key.Runes))
+		for step := 1; step <= len(p.choices); step++ {
+			i := (p.selected + step + len(p.choices)) % len(p.choices)
+			if strings.HasPrefix(strings.ToLower(p.choices[i]), typed) {
+				p.selected = i
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// View lists every choice with the selected one highlighted when focused, and just
+// the value otherwise
+func (p *picker) View(focused bool) string {
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+	if !focused {
+		if p.selected < 0 {
+			return muted.Render("--")
+		}
+		return valueStyle.Render(p.Value())
+	}
+
+	parts := make([]string, 0, len(p.choices)+1)
+	for i, c := range append([]string{"--"}, p.choices...) {
+		if i-1 == p.selected {
+			parts = append(parts, selectedStyle.Render("["+c+"]"))
+		} else {
+			parts = append(parts, muted.Render(c))
+		}
+	}
+	return muted.Render("◂ ") + strings.Join(parts, " ") + muted.Render(" ▸")
+}
+
+// checkOption is one tag in a checklist
+type checkOption struct {
+	tag     string
+	name    string
+	checked bool
+}
+
+// checklist selects any number of tags. Typing filters the list fuzzily by tag
+// and name, up/down move the cursor and space toggles the tag under it.
+type checklist struct {
+	options []checkOption
+	filter  textinput.Model
+	cursor  int // index into the filtered options
+}
+
+// checklistRows is how many options are shown at once
+const checklistRows = 8
+
+func newChecklist(options []checkOption) *checklist {
+	ti := textinput.New()
+	ti.Prompt = "filter: "
+	ti.CharLimit = 64
+	return &checklist{options: slices.Clone(options), filter: ti}
+}
+
+func (c *checklist) Focus() {
+	c.filter.Focus()
+}
+
+func (c *checklist) Blur() {
+	c.filter.Blur()
+	c.filter.SetValue("")
+	c.cursor = 0
+}
+
+func (c *checklist) Value() string {
+	var tags []string
+	for _, o := range c.options {
+		if o.checked {
+			tags = append(tags, o.tag)
+		}
+	}
+	return strings.Join(tags, " ")
+}
+
+// SetValue checks the given space-separated tags; unknown tags are kept as extra options
+func (c *checklist) SetValue(s string) {
+	tags := splitTags(s)
+	for i := range c.options {
+		c.options[i].checked = slices.Contains(tags, c.options[i].tag)
+	}
+	for _, tag := range tags {
+		if !slices.ContainsFunc(c.options, func(o checkOption) bool { return o.tag == tag }) {
+			c.options = append(c.options, checkOption{tag: tag, checked: true})
+		}
+	}
+}
+
+// visible returns the indexes of the options matching the filter
+func (c *checklist) visible() []int {
+	query := strings.ToLower(c.filter.Value())
+	var idx []int
+	for i, o := range c.options {
+		if fuzzyMatch(query, strings.ToLower(o.tag)) || fuzzyMatch(query, strings.ToLower(o.name)) {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+func (c *checklist) Update(msg tea.Msg) tea.Cmd {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		visible := c.visible()
+		switch key.String() {
+		case "up":
+			if c.cursor > 0 {
+				c.cursor--
+			}
+			return nil
+		case "down":
+			if c.cursor < len(visible)-1 {
+				c.cursor++
+			}
+			return nil
+		case " ":
+			if c.cursor < len(visible) {
+				o := &c.options[visible[c.cursor]]
+				o.checked = !o.checked
+			}
+			return nil
+		}
+	}
+
+	var cmd tea.Cmd
+	c.filter, cmd = c.filter.Update(msg)
+	c.cursor = min(c.cursor, max(len(c.visible())-1, 0))
+	return cmd
+}
+
+func (c *checklist) View(focused bool) string {
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+	if !focused {
+		var tags []string
+		for _, o := range c.options {
+			if o.checked {
+				tags = append(tags, o.tag)
+			}
+		}
+		if len(tags) == 0 {
+			return muted.Render("none")
+		}
+		return valueStyle.Render(strings.Join(tags, " "))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(c.filter.View())
+
+	visible := c.visible()
+	if len(visible) == 0 {
+		sb.WriteString("\n" + labelStyle.Render("") + "   " + muted.Render("no match"))
+	}
+	first := max(0, min(c.cursor-checklistRows/2, len(visible)-checklistRows))
+	for row := first; row < len(visible) && row < first+checklistRows; row++ {
+		o := c.options[visible[row]]
+		cursor := "  "
+		if row == c.cursor {
+			cursor = selectedStyle.Render("› ")
+		}
+		box := muted.Render("[ ]")
+		if o.checked {
+			box = successStyle.Render("[x]")
+		}
+		line := cursor + box + " " + valueStyle.Render(o.tag)
+		if o.name != "" {
+			line += " " + muted.Render(o.name)
+		}
+		sb.WriteString("\n" + labelStyle.Render("") + " " + line)
+	}
+	return sb.String()
+}
+
+// fuzzyMatch reports whether the characters of query appear in s in order
+func fuzzyMatch(query, s string) bool {
+	for _, r := range query {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
+}
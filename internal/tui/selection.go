@@ -0,0 +1,269 @@
+package tui
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/bf/tg/internal/config"
+	"github.com/bf/tg/internal/llm"
+)
+
+// selectionItem is one suggested value in the preview that can be accepted or rejected
+type selectionItem struct {
+	field   string // enrichment field, e.g. "beacons", "effort"
+	label   string
+	value   string
+	choices []string // allowed values cycled with left/right, nil for free-form fields
+	hint    string
+	on      bool
+}
+
+// selection lets the user pick which suggested values are applied. Every beacon and
+// direction is its own item; scalar fields are one item each.
+type selection struct {
+	items  []selectionItem
+	cursor int
+}
+
+func newSelection(e *llm.Enrichment, cfg *config.Config) *selection {
+	var beaconTags, directionTags, projects []string
+	for _, b := range cfg.Beacons {
+		beaconTags = append(beaconTags, b.Tag)
+		for _, d := range b.Directions {
+			if !slices.Contains(directionTags, d.Tag) {
+				directionTags = append(directionTags, d.Tag)
+			}
+		}
+	}
+	for _, p := range cfg.Projects {
+		projects = append(projects, p.Name)
+	}
+
+	s := &selection{}
+	tags := func(field, label string, values, choices []string) {
+		if len(values) == 0 {
+			// An empty row to pick a value into with left/right
+			s.add(field, label, "", choices, "")
+		}
+		for _, v := range values {
+			s.add(field, label, v, choices, "")
+		}
+	}
+	tags("beacons", "Beacons", e.Beacons, beaconTags)
+	tags("directions", "Directions", e.Directions, directionTags)
+
+	allowed := cfg.UDAValues
+	s.add("project", "Project", e.Project, projects, "")
+	s.add("priority", "Priority", e.Priority, allowed.Priority, "")
+	s.add("due", "Due", e.Due, nil, "hard deadline")
+	s.add("scheduled", "Scheduled", e.Scheduled, nil, "soft due date")
+	s.add("effort", "Effort", e.Effort, allowed.Effort, "E=Easy N=Normal D=Difficult")
+	s.add("impact", "Impact", e.Impact, allowed.Impact, "H=High M=Medium L=Low")
+	s.add("estimate", "Estimate", e.Estimate, allowed.Estimate, "")
+	s.add("fun", "Fun", e.Fun, allowed.Fun, "H=Fun M=Neutral L=Boring")
+	s.add("blocks", "Blocks", blocksValue(e.Blocks), nil, "")
+	if e.IsWaste {
+		s.add("waste", "Waste", "yes", nil, "tag as +waste")
+	}
+	return s
+}
+
+// annotationLabels names the annotation rows by kind
+var annotationLabels = map[string]string{
+	"step":      "Step",
+	"reasoning": "Note",
+	"link":      "Link",
+}
+
+// addAnnotations adds a row for each annotation tg add can attach to the new task
+func (s *selection) addAnnotations(e *llm.Enrichment) {
+	for _, a := range e.Annotations() {
+		s.add("annotation", annotationLabels[a.Kind], a.Text, nil, "")
+	}
+}
+
+// keepAnnotations carries over which annotations were deselected in prev, e.g.
+// when the selection is rebuilt after edit mode
+func (s *selection) keepAnnotations(prev *selection) {
+	kept := prev.annotations()
+	for i := range s.items {
+		if it := &s.items[i]; it.field == "annotation" {
+			it.on = slices.Contains(kept, it.value)
+		}
+	}
+}
+
+// annotations returns the annotations left selected
+func (s *selection) annotations() []string {
+	var texts []string
+	for _, it := range s.items {
+		if it.field == "annotation" && it.on {
+			texts = append(texts, it.value)
+		}
+	}
+	return texts
+}
+
+func (s *selection) add(field, label, value string, choices []string, hint string) {
+	s.items = append(s.items, selectionItem{
+		field:   field,
+		label:   label,
+		value:   value,
+		choices: choices,
+		hint:    hint,
+		on:      value != "",
+	})
+}
+
+func blocksValue(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// handleKey moves the cursor, toggles or cycles the current item; other keys are ignored
+func (s *selection) handleKey(key string) {
+	item := &s.items[s.cursor]
+	switch key {
+	case "up", "k":
+		s.cursor = (s.cursor - 1 + len(s.items)) % len(s.items)
+	case "down", "j":
+		s.cursor = (s.cursor + 1) % len(s.items)
+	case " ":
+		// Nothing to accept on an empty row
+		item.on = !item.on && item.value != ""
+	case "left", "h":
+		item.cycle(-1)
+	case "right", "l":
+		item.cycle(1)
+	}
+}
+
+// cycle steps through the allowed values; picking a value also selects it
+func (it *selectionItem) cycle(step int) {
+	if it.field == "blocks" {
+		n, _ := strconv.Atoi(it.value)
+		it.value = blocksValue(max(n+step, 0))
+		it.on = it.value != ""
+		return
+	}
+	if len(it.choices) == 0 {
+		return
+	}
+
+	i := slices.Index(it.choices, it.value)
+	switch {
+	case i < 0 && step > 0:
+		i = 0
+	case i < 0:
+		i = len(it.choices) - 1
+	default:
+		i = (i + step + len(it.choices)) % len(it.choices)
+	}
+	it.value = it.choices[i]
+	it.on = true
+}
+
+// apply returns a copy of e that carries only the selected values
+func (s *selection) apply(e *llm.Enrichment) *llm.Enrichment {
+	out := *e
+	out.Beacons, out.Directions = nil, nil
+	out.Project, out.Priority, out.Due, out.Scheduled = "", "", "", ""
+	out.Effort, out.Impact, out.Estimate, out.Fun = "", "", "", ""
+	out.Blocks = 0
+	out.IsWaste = false
+
+	for _, it := range s.items {
+		if !it.on {
+			continue
+		}
+		switch it.field {
+		case "beacons":
+			if !slices.Contains(out.Beacons, it.value) {
+				out.Beacons = append(out.Beacons, it.value)
+			}
+		case "directions":
+			if !slices.Contains(out.Directions, it.value) {
+				out.Directions = append(out.Directions, it.value)
+			}
+		case "project":
+			out.Project = it.value
+		case "priority":
+			out.Priority = it.value
+		case "due":
+			out.Due = it.value
+		case "scheduled":
+			out.Scheduled = it.value
+		case "effort":
+			out.Effort = it.value
+		case "impact":
+			out.Impact = it.value
+		case "estimate":
+			out.Estimate = it.value
+		case "fun":
+			out.Fun = it.value
+		case "blocks":
+			out.Blocks, _ = strconv.Atoi(it.value)
+		case "waste":
+			out.IsWaste = true
+		}
+	}
+	return &out
+}
+
+// view renders one row per item: a cursor, a checkbox and the value, with
+// arrows around values that can be cycled and the resolved form of dates
+func (s *selection) view(dates map[string]dateResult) string {
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+
+	var sb strings.Builder
+	for i, it := range s.items {
+		cursor := "  "
+		if i == s.cursor {
+			cursor = selectedStyle.Render("› ")
+		}
+		box := muted.Render("[ ]")
+		if it.on {
+			box = successStyle.Render("[x]")
+		}
+
+		text := it.value
+		if it.field == "annotation" {
+			text = truncateText(text, 70)
+		}
+		value := muted.Render("--")
+		if it.value != "" {
+			switch {
+			case !it.on:
+				value = muted.Strikethrough(true).Render(text)
+			case it.field == "beacons":
+				value = tagStyle.Render(it.value)
+			case it.field == "directions":
+				value = directionTagStyle.Render(it.value)
+			default:
+				value = valueStyle.Render(text)
+			}
+		}
+		if (it.field == "due" || it.field == "scheduled") && it.value != "" {
+			value += " " + formatResolved(it.value, dates)
+		}
+		if i == s.cursor && (len(it.choices) > 0 || it.field == "blocks") {
+			value = muted.Render("◂ ") + value + muted.Render(" ▸")
+		}
+		hint := it.hint
+		if it.field == "blocks" {
+			n, _ := strconv.Atoi(it.value)
+			hint = blocksHint(n)
+		}
+		if hint != "" {
+			value += " " + muted.Render("("+hint+")")
+		}
+
+		sb.WriteString(cursor + box + " " + labelStyle.Render(it.label+":") + " " + value + "\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
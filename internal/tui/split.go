@@ -0,0 +1,250 @@
+package tui
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/bf/tg/internal/llm"
+	"github.com/bf/tg/internal/taskwarrior"
+)
+
+type splitMsg struct {
+	split *llm.Split
+	err   error
+}
+
+type splitAddedMsg struct {
+	added int
+	err   error
+}
+
+// SetSplit makes tg add break the task into subtasks right away, as with --split,
+// instead of enriching it as one task
+func (m *AddModel) SetSplit(split bool) {
+	m.splitFirst = split
+	if split {
+		m.state = stateSplitting
+	}
+}
+
+// startSplit asks for subtasks from the preview, keeping the enrichment to go back to
+func (m *AddModel) startSplit() tea.Cmd {
+	m.notice = ""
+	m.state = stateSplitting
+	return tea.Batch(m.spinner.Tick, m.fetchSplit())
+}
+
+func (m *AddModel) fetchSplit() tea.Cmd {
+	progress := make(chan llm.Progress, 1)
+	m.progressCh = progress
+
+	// What the preview already settled on is context for the subtasks
+	task := m.parentTask()
+	fetch := func() tea.Msg {
+		defer close(progress)
+		split, err := m.provider.Split(
+			llm.WithProgress(context.Background(), relayProgress(progress)),
+			task,
+			m.cfg.Beacons,
+			m.cfg.Projects,
+		)
+		return splitMsg{split: split, err: err}
+	}
+	return tea.Batch(fetch, listenProgress(progress))
+}
+
+func (m *AddModel) handleSplit(msg splitMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		if m.enrichment != nil {
+			// The enrichment is still good; say why and go back to it
+			m.notice = "Couldn't split the task: " + msg.err.Error()
+			m.state = statePreview
+			return m, nil
+		}
+		m.err = msg.err
+		m.state = stateError
+		return m, nil
+	}
+
+	m.split = msg.split
+	m.splitFixes = llm.ValidateSplit(m.split, m.cfg)
+	m.withParent = true
+	m.state = stateSplitPreview
+	return m, nil
+}
+
+// parentTask is the task being split: the values selected in the preview, or just
+// the description with --split. It becomes the tracking task.
+func (m *AddModel) parentTask() taskwarrior.Task {
+	if m.enrichment == nil {
+		task := taskwarrior.Task{Description: m.original}
+		if m.split != nil {
+			task.Project = m.split.Project
+		}
+		return task
+	}
+	return enrichedTask(m.selection.apply(m.enrichment))
+}
+
+// addSplit adds the subtasks in order, each depending on the earlier ones it names,
+// then the tracking task depending on all of them. It stops at the first failure; the
+// error points to tg undo, which removes what this run added.
+func (m *AddModel) addSplit() tea.Cmd {
+	split := m.split
+	parent := m.parentTask()
+	withParent := m.withParent
+	project := cmp.Or(parent.Project, split.Project)
+
+	return func() tea.Msg {
+		var uuids []string
+		for _, st := range split.Subtasks {
+			task := taskwarrior.Task{
+				Description: st.Description,
+				Project:     project,
+				Effort:      st.Effort,
+				Estimate:    st.Estimate,
+			}
+			task.Tags = append(task.Tags, st.Beacons...)
+			task.Tags = append(task.Tags, st.Directions...)
+			for _, n := range st.DependsOn {
+				task.Depends = append(task.Depends, uuids[n-1])
+			}
+
+			uuid, err := m.twClient.Add(&task)
+			if err != nil {
+				err = fmt.Errorf("failed to add subtask %d: %w", len(uuids)+1, err)
+				if len(uuids) > 0 {
+					err = fmt.Errorf("%w\nthe %d added before it have no tracking task; run `tg undo` to remove them", err, len(uuids))
+				}
+				return splitAddedMsg{added: len(uuids), err: err}
+			}
+			uuids = append(uuids, uuid)
+		}
+
+		if !withParent {
+			return splitAddedMsg{added: len(uuids)}
+		}
+		parent.Project = project
+		parent.Depends = uuids
+		if _, err := m.twClient.Add(&parent); err != nil {
+			return splitAddedMsg{added: len(uuids), err: fmt.Errorf("subtasks added, but not the tracking task (run `tg undo` to remove them): %w", err)}
+		}
+		return splitAddedMsg{added: len(uuids) + 1}
+	}
+}
+
+func (m *AddModel) handleSplitKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		if m.enrichment == nil {
+			return m, tea.Quit
+		}
+		m.split = nil
+		m.state = statePreview
+	case "p":
+		m.withParent = !m.withParent
+	case "enter", "a":
+		return m, m.addSplit()
+	}
+	return m, nil
+}
+
+func (m *AddModel) viewSplitting() string {
+	return fmt.Sprintf("\n  %s Breaking the task down into subtasks...%s\n\n  %s\n",
+		m.spinner.View(),
+		formatAttempt(m.progress),
+		subtitleStyle.Render(m.original),
+	)
+}
+
+func (m *AddModel) viewSplitPreview() string {
+	var sb strings.Builder
+
+	sb.WriteString(titleStyle.Render("tg add - split") + "\n\n")
+	sb.WriteString(labelStyle.Render("Original:") + " " + subtitleStyle.Render(m.original) + "\n\n")
+	if m.split.Provider != "" {
+		sb.WriteString(labelStyle.Render("Provider:") + " " + valueStyle.Render(m.split.Provider) + "\n\n")
+	}
+
+	var content strings.Builder
+	content.WriteString(formatSplitTree(m.parentTask(), m.split, m.withParent) + "\n")
+	if project := cmp.Or(m.parentTask().Project, m.split.Project); project != "" {
+		content.WriteString("\n" + labelStyle.Render("Project:") + " " + valueStyle.Render(project) + "\n")
+	}
+	content.WriteString(formatUsage(m.split.Usage))
+	if len(m.splitFixes) > 0 {
+		content.WriteString("\n" + formatFixes(m.splitFixes))
+	}
+	if m.split.Reasoning != "" {
+		content.WriteString("\n" + subtitleStyle.Render(m.split.Reasoning))
+	}
+
+	sb.WriteString(boxStyle.Render(content.String()))
+	sb.WriteString("\n\n")
+	back := "Back"
+	if m.enrichment == nil {
+		back = "Cancel"
+	}
+	sb.WriteString(helpStyle.Render("[p] Tracking task on/off  [enter/a] Add all  [esc] " + back + "  [q] Quit"))
+
+	return sb.String()
+}
+
+// formatSplitTree shows the tracking task with the subtasks under it, numbered in
+// order with their estimate, effort, tags and the subtasks they wait for
+func formatSplitTree(parent taskwarrior.Task, split *llm.Split, withParent bool) string {
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+
+	var sb strings.Builder
+	box := muted.Render("[ ]")
+	title := muted.Strikethrough(true).Render(parent.Description)
+	if withParent {
+		box = successStyle.Render("[x]")
+		title = valueStyle.Render(parent.Description)
+	}
+	sb.WriteString(box + " " + title + " " + muted.Render("(tracking task, depends on all)") + "\n")
+
+	for i, st := range split.Subtasks {
+		branch, indent := "├─ ", "│     "
+		if i == len(split.Subtasks)-1 {
+			branch, indent = "└─ ", "      "
+		}
+		sb.WriteString(muted.Render(branch) + fmt.Sprintf("%d. ", i+1) + valueStyle.Render(st.Description) + "\n")
+
+		var details []string
+		if st.Estimate != "" {
+			details = append(details, st.Estimate)
+		}
+		if st.Effort != "" {
+			details = append(details, "effort "+st.Effort)
+		}
+		var tags []string
+		for _, tag := range st.Beacons {
+			tags = append(tags, tagStyle.Render(tag))
+		}
+		for _, tag := range st.Directions {
+			tags = append(tags, directionTagStyle.Render(tag))
+		}
+		if len(st.DependsOn) > 0 {
+			after := make([]string, len(st.DependsOn))
+			for j, n := range st.DependsOn {
+				after[j] = fmt.Sprint(n)
+			}
+			details = append(details, "after "+strings.Join(after, ", "))
+		}
+		var line []string
+		if len(details) > 0 {
+			line = append(line, muted.Render(strings.Join(details, " · ")))
+		}
+		line = append(line, tags...)
+		sb.WriteString(muted.Render(indent) + strings.Join(line, " ") + "\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -45,6 +45,12 @@ var (
 			Padding(0, 1).
 			Bold(true)
 
+	cachedTagStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("0")).
+			Background(successColor).
+			Padding(0, 1).
+			Bold(true)
+
 	boxStyle = lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
 			BorderForeground(primaryColor).
@@ -61,6 +67,9 @@ var (
 			Foreground(successColor).
 			Bold(true)
 
+	warningStyle = lipgloss.NewStyle().
+			Foreground(warningColor)
+
 	errorStyle = lipgloss.NewStyle().
 			Foreground(errorColor).
 			Bold(true)
@@ -0,0 +1,122 @@
+package usage
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// Row totals the calls sharing a day, provider or command
+type Row struct {
+	Key          string
+	Calls        int
+	InputTokens  int
+	OutputTokens int
+	Latency      time.Duration // total, divide by Calls for the average
+	Cost         float64
+	Unpriced     int // calls whose model had no configured price
+}
+
+func (r *Row) add(rec Record) {
+	r.Calls++
+	r.InputTokens += rec.InputTokens
+	r.OutputTokens += rec.OutputTokens
+	r.Latency += time.Duration(rec.LatencyMS) * time.Millisecond
+	r.Cost += rec.Cost
+	if rec.Unpriced {
+		r.Unpriced++
+	}
+}
+
+// Summary groups the records made since a point in time
+type Summary struct {
+	ByDay      []Row // oldest first
+	ByProvider []Row // costliest first
+	ByCommand  []Row // costliest first
+	Total      Row
+}
+
+// Summarize totals the records made at or after since
+func Summarize(records []Record, since time.Time) Summary {
+	days := make(map[string]*Row)
+	providers := make(map[string]*Row)
+	commands := make(map[string]*Row)
+	group := func(rows map[string]*Row, key string, rec Record) {
+		if rows[key] == nil {
+			rows[key] = &Row{Key: key}
+		}
+		rows[key].add(rec)
+	}
+
+	var s Summary
+	for _, rec := range records {
+		if rec.Time.Before(since) {
+			continue
+		}
+		group(days, rec.Time.Local().Format("2006-01-02"), rec)
+		group(providers, rec.Provider+"/"+rec.Model, rec)
+		group(commands, rec.Command, rec)
+		s.Total.add(rec)
+	}
+	s.Total.Key = "total"
+
+	s.ByDay = sortedRows(days, func(a, b Row) int { return cmp.Compare(a.Key, b.Key) })
+	byCost := func(a, b Row) int { return cmp.Or(cmp.Compare(b.Cost, a.Cost), cmp.Compare(a.Key, b.Key)) }
+	s.ByProvider = sortedRows(providers, byCost)
+	s.ByCommand = sortedRows(commands, byCost)
+	return s
+}
+
+func sortedRows(rows map[string]*Row, compare func(a, b Row) int) []Row {
+	sorted := make([]Row, 0, len(rows))
+	for _, r := range rows {
+		sorted = append(sorted, *r)
+	}
+	slices.SortFunc(sorted, compare)
+	return sorted
+}
+
+// WriteReport writes one table per grouping followed by the total
+func WriteReport(w io.Writer, s Summary) {
+	for _, section := range []struct {
+		title string
+		rows  []Row
+	}{
+		{"DAY", s.ByDay},
+		{"PROVIDER", s.ByProvider},
+		{"COMMAND", s.ByCommand},
+	} {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "%s\tCALLS\tINPUT\tOUTPUT\tAVG LATENCY\tCOST\n", section.title)
+		for _, r := range section.rows {
+			writeRow(tw, r)
+		}
+		tw.Flush()
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "Total: %d calls, %d input + %d output tokens, %s\n",
+		s.Total.Calls, s.Total.InputTokens, s.Total.OutputTokens, FormatCost(s.Total.Cost))
+	if s.Total.Unpriced > 0 {
+		fmt.Fprintf(w, "%d calls used models without a configured price and count as free\n", s.Total.Unpriced)
+	}
+}
+
+func writeRow(w io.Writer, r Row) {
+	latency := "--"
+	if r.Calls > 0 {
+		latency = (r.Latency / time.Duration(r.Calls)).Round(10 * time.Millisecond).String()
+	}
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\n", r.Key, r.Calls, r.InputTokens, r.OutputTokens, latency, FormatCost(r.Cost))
+}
+
+// FormatCost renders a USD amount with enough precision for single calls
+func FormatCost(usd float64) string {
+	if usd < 1 {
+		return fmt.Sprintf("$%.4f", usd)
+	}
+	return fmt.Sprintf("$%.2f", usd)
+}
@@ -0,0 +1,99 @@
+// Package usage keeps a local log of LLM calls - tokens, latency and estimated cost -
+// and reports spend by day, provider and tg command.
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bf/tg/internal/config"
+)
+
+// Record is one LLM call
+type Record struct {
+	Time         time.Time `json:"time"`
+	Command      string    `json:"command"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	LatencyMS    int64     `json:"latency_ms"`
+	Cost         float64   `json:"cost"`               // USD at the prices configured when the call was made
+	Unpriced     bool      `json:"unpriced,omitempty"` // no price was configured for the model
+}
+
+// Log is an append-only JSONL file of LLM calls
+type Log struct {
+	path string
+}
+
+func OpenLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// DefaultLogPath returns usage.jsonl in tg's state directory
+func DefaultLogPath() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "usage.jsonl"), nil
+}
+
+// Append writes a record, stamping it with the current time if unset
+func (l *Log) Append(r Record) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage record: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create usage dir: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open usage log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage log: %w", err)
+	}
+	return nil
+}
+
+// Records reads the whole log in the order it was written
+func (l *Log) Records() ([]Record, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage log: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue // skip a torn line rather than lose the whole log
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage log: %w", err)
+	}
+	return records, nil
+}